@@ -0,0 +1,89 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+var truncatedMarkerRegexp = regexp.MustCompile(`(?m)^\[\.\.\. [0-9]+ bytes truncated \.\.\.\]\n`)
+
+// Limits for the captured stdout and stderr of a command, output is
+// captured completely if neither Head nor Tail is set
+type CaptureLimit struct {
+	// Bytes kept from the beginning of the output
+	Head int
+
+	// Bytes kept from the end of the output
+	Tail int
+
+	// Write the complete output to a temp file if it is truncated
+	// (see Process.StdoutFile and Process.StderrFile)
+	Spill bool
+}
+
+// Check if output is limited
+func (l CaptureLimit) enabled() bool {
+	return l.Head > 0 || l.Tail > 0
+}
+
+// Marker appended to the head (and followed by the tail) of truncated output
+func truncatedMarker(n int64) string {
+	return fmt.Sprintf("[... %v bytes truncated ...]\n", n)
+}
+
+// Last lines of truncated output, taken from the retained tail
+// (prefixed by the marker) if there is one
+func truncatedTail(output string, lines int) string {
+	markers := truncatedMarkerRegexp.FindAllStringIndex(output, -1)
+	if len(markers) == 0 {
+		return stderrTail(output, lines)
+	}
+	marker := markers[len(markers)-1]
+	if marker[1] == len(output) {
+		return stderrTail(output, lines)
+	}
+	return output[marker[0]:marker[1]] + stderrTail(output[marker[1]:], lines)
+}
+
+// Buffer keeping the last bytes written to it
+type ringBuffer struct {
+	buf  []byte
+	pos  int
+	full bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{buf: make([]byte, size)}
+}
+
+func (r *ringBuffer) Write(p []byte) (int, error) {
+	size := len(r.buf)
+	if len(p) >= size {
+		copy(r.buf, p[len(p)-size:])
+		r.pos = 0
+		r.full = true
+		return len(p), nil
+	}
+
+	n := copy(r.buf[r.pos:], p)
+	copy(r.buf, p[n:])
+	if r.pos+len(p) >= size {
+		r.full = true
+	}
+	r.pos = (r.pos + len(p)) % size
+	return len(p), nil
+}
+
+// Content of the buffer, oldest byte first
+func (r *ringBuffer) Bytes() []byte {
+	if !r.full {
+		return r.buf[:r.pos]
+	}
+	return append(append([]byte(nil), r.buf[r.pos:]...), r.buf[:r.pos]...)
+}
+
+// Create temp file for the complete output of a command
+func createSpillFile() (*os.File, error) {
+	return os.CreateTemp("", "go-shell-output-*")
+}
@@ -0,0 +1,85 @@
+package shell
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRingBuffer(t *testing.T) {
+	r := newRingBuffer(5)
+	r.Write([]byte("abc"))
+	if string(r.Bytes()) != "abc" {
+		t.Fatal("output not expected:", string(r.Bytes()))
+	}
+	r.Write([]byte("de"))
+	if string(r.Bytes()) != "abcde" {
+		t.Fatal("output not expected:", string(r.Bytes()))
+	}
+	r.Write([]byte("fg"))
+	if string(r.Bytes()) != "cdefg" {
+		t.Fatal("output not expected:", string(r.Bytes()))
+	}
+	r.Write([]byte("0123456789"))
+	if string(r.Bytes()) != "56789" {
+		t.Fatal("output not expected:", string(r.Bytes()))
+	}
+}
+
+func TestCaptureLimit(t *testing.T) {
+	r := NewRunner()
+	r.Panic = false
+	r.Capture = CaptureLimit{Head: 8, Tail: 8}
+
+	p := r.Cmd("seq 1 1000").Run()
+	if !p.Truncated || p.StdoutFile != "" {
+		t.Fatal("process not expected:", p.Truncated, p.StdoutFile)
+	}
+	expected := "1\n2\n3\n4\n[... 3877 bytes truncated ...]\n99\n1000\n"
+	if p.Stdout.String() != expected {
+		t.Fatal("output not expected:", p.Stdout.String())
+	}
+
+	p = r.Cmd("seq 1 5").Run()
+	if p.Truncated || p.Stdout.String() != "1\n2\n3\n4\n5\n" {
+		t.Fatal("output not expected:", p.Truncated, p.Stdout.String())
+	}
+
+	p, err := r.Cmd("seq 1 1000 >&2; exit 1").RunE()
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Stderr != "[... 3877 bytes truncated ...]\n99\n1000\n" || len(p.Stderr.String()) > 64 {
+		t.Fatal("error not expected:", err, p.Stderr.String())
+	}
+
+	r.Capture = CaptureLimit{Head: 8}
+	p = r.Cmd("seq 1 1000").Run()
+	if !p.Truncated || p.Stdout.String() != "1\n2\n3\n4\n[... 3885 bytes truncated ...]\n" {
+		t.Fatal("output not expected:", p.Truncated, p.Stdout.String())
+	}
+}
+
+func TestCaptureSpill(t *testing.T) {
+	r := NewRunner()
+	r.Capture = CaptureLimit{Tail: 4, Spill: true}
+
+	p := r.Cmd("seq 1 1000").Run()
+	defer os.Remove(p.StdoutFile)
+	if !p.Truncated || p.StdoutFile == "" || p.StderrFile != "" {
+		t.Fatal("process not expected:", p.Truncated, p.StdoutFile, p.StderrFile)
+	}
+	if !strings.HasSuffix(p.Stdout.String(), "truncated ...]\n000\n") {
+		t.Fatal("output not expected:", p.Stdout.String())
+	}
+
+	spilled, err := os.ReadFile(p.StdoutFile)
+	if err != nil || !bytes.Equal(spilled, Cmd("seq 1 1000").Run().Bytes()) {
+		t.Fatal("spilled output not expected:", len(spilled), err)
+	}
+
+	p = r.Cmd("echo foo").Run()
+	if p.Truncated || p.StdoutFile != "" {
+		t.Fatal("output spilled:", p.StdoutFile)
+	}
+}
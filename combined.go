@@ -0,0 +1,108 @@
+package shell
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Output stream of a command
+type Stream int
+
+const (
+	StreamStdout Stream = 1
+	StreamStderr Stream = 2
+)
+
+func (s Stream) String() string {
+	switch s {
+	case StreamStdout:
+		return "stdout"
+	case StreamStderr:
+		return "stderr"
+	}
+	return "unknown"
+}
+
+// Chunk of output in the order it was written by the command
+type Chunk struct {
+	// Stream the chunk was written to
+	Stream Stream
+
+	// Output of the chunk
+	Data []byte
+
+	// Time the chunk was read (including the monotonic clock)
+	Time time.Time
+
+	// Time since the command was started
+	Offset time.Duration
+}
+
+// Interleaved stdout and stderr of a command
+type combinedCapture struct {
+	mu     sync.Mutex
+	start  time.Time
+	chunks []Chunk
+}
+
+// Writer adding chunks of stream
+func (c *combinedCapture) writer(stream Stream) io.Writer {
+	return &combinedWriter{capture: c, stream: stream}
+}
+
+type combinedWriter struct {
+	capture *combinedCapture
+	stream  Stream
+}
+
+func (w *combinedWriter) Write(b []byte) (int, error) {
+	c := w.capture
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.chunks = append(c.chunks, Chunk{
+		Stream: w.stream,
+		Data:   append([]byte(nil), b...),
+		Time:   now,
+		Offset: now.Sub(c.start),
+	})
+	return len(b), nil
+}
+
+// Copy of all chunks
+func (c *combinedCapture) Chunks() []Chunk {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Chunk(nil), c.chunks...)
+}
+
+// Capture writer for stream, chunks are added to the combined
+// capture if enabled
+func (pl *pipeline) capture(stream Stream) io.Writer {
+	var buf io.Writer = pl.stdout
+	if stream == StreamStderr {
+		buf = pl.stderr
+	}
+	if pl.combined == nil {
+		return buf
+	}
+	return io.MultiWriter(buf, pl.combined.writer(stream))
+}
+
+// Interleaved stdout and stderr chunks in the order they were written,
+// only recorded if CaptureCombined of the runner is enabled. The order
+// of chunks written at almost the same time to different streams is
+// not guaranteed as both streams are read concurrently.
+func (p *Process) Combined() []Chunk {
+	return p.combined
+}
+
+// Output of all chunks like shown on a terminal
+func (p *Process) transcript() string {
+	var ret []byte
+	for _, chunk := range p.combined {
+		ret = append(ret, chunk.Data...)
+	}
+	return string(ret)
+}
@@ -0,0 +1,37 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCombined(t *testing.T) {
+	r := NewRunner()
+	r.Panic = false
+	r.CaptureCombined = true
+
+	p := r.Cmd("echo out1; sleep 0.1; echo err1 >&2; sleep 0.1; echo out2; exit 1").Run()
+	chunks := p.Combined()
+	if len(chunks) != 3 {
+		t.Fatal("chunks not expected:", chunks)
+	}
+	for i, expected := range []Stream{StreamStdout, StreamStderr, StreamStdout} {
+		if chunks[i].Stream != expected {
+			t.Fatal("stream not expected:", i, chunks[i].Stream)
+		}
+	}
+	if string(chunks[1].Data) != "err1\n" || chunks[1].Offset <= chunks[0].Offset || chunks[2].Offset <= chunks[1].Offset {
+		t.Fatal("chunk not expected:", chunks[1])
+	}
+	if p.String() != "out1\nout2" || p.Stderr.String() != "err1\n" {
+		t.Fatal("output not expected:", p.String(), p.Stderr.String())
+	}
+	if !strings.Contains(p.Debug(), "OUTPUT:    out1\n           err1\n           out2") {
+		t.Fatal("debug output not expected:", p.Debug())
+	}
+
+	r.CaptureCombined = false
+	if p := r.Cmd("echo foobar").Run(); p.Combined() != nil {
+		t.Fatal("chunks not expected:", p.Combined())
+	}
+}
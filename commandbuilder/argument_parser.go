@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"errors"
 	"github.com/mohae/deepcopy"
+	"github.com/webdevops/go-shell"
 )
 
 var argParserDsn = regexp.MustCompile("^(?P<schema>[-_a-zA-Z0-9]+):((?P<hostname>[^/;=]+)(?P<param1>;[^/;]+.*)*|(?P<param2>[^=]+=[^;]+)(?P<param3>;[^/;]+.*)*)$")
@@ -77,6 +78,11 @@ func (argument *Argument) Set(value string) error {
 		argument.simple = true
 	}
 
+	// passwords must not show up in trace, debug and error output
+	if argument.Password != "" {
+		shell.AddSecret(argument.Password)
+	}
+
 	return nil
 }
 // Clone connection
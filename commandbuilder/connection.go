@@ -3,7 +3,9 @@ package commandbuilder
 import (
 	"strings"
 	"fmt"
+	"time"
 	"github.com/mohae/deepcopy"
+	"github.com/webdevops/go-shell"
 )
 
 var (
@@ -12,6 +14,9 @@ var (
 
 	// Default Docker options
 	ConnectionDockerArguments = []string{"exec", "-i"}
+
+	// Timeout for docker-compose container id lookups (0 = no timeout)
+	ConnectionDockerComposeTimeout = time.Duration(0)
 )
 
 type Environment struct {
@@ -104,7 +109,7 @@ func (connection *Connection) String() string {
 	default:
 	}
 
-	return fmt.Sprintf("Connection[%s]", strings.Join(parts[:]," "))
+	return shell.Redact(fmt.Sprintf("Connection[%s]", strings.Join(parts[:]," ")))
 }
 
 // Check if environment is empty
@@ -127,6 +132,13 @@ func (env *Environment) Set(name string, value string) {
 	env.Vars[name] = value
 }
 
+// Set one environment variable containing a secret, the value
+// is redacted in trace, debug and error output
+func (env *Environment) SetSecret(name string, value string) {
+	shell.AddSecret(value)
+	env.Set(name, value)
+}
+
 // Add environment map (adds/overwrites)
 func (env *Environment) AddMap(vars map[string]string) {
 	for name, val := range vars {
@@ -79,7 +79,7 @@ func (connection *Connection) queryDockerContainerId() string {
 		dockerComposeArgs = append(dockerComposeArgs, "ps", "-q", shell.Quote(connection.Docker.Hostname))
 
 		// query container id from docker-compose
-		cmd := shell.Cmd(connectionClone.RawCommandBuilder("docker-compose", dockerComposeArgs...)...).Run()
+		cmd := shell.Cmd(connectionClone.RawCommandBuilder("docker-compose", dockerComposeArgs...)...).WithTimeout(ConnectionDockerComposeTimeout).Run()
 		ret = strings.TrimSpace(cmd.Stdout.String())
 
 		if ret == "" {
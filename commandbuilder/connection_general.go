@@ -82,15 +82,17 @@ func (connection *Connection) RawShellCommandBuilder(args ...string) []interface
 	inlineCommand = shell.Quote(inlineCommand)
 
 
+	sh := shellArgv()
+
 	switch connection.GetType() {
 	case "local":
-		ret = connection.LocalCommandBuilder(shell.Shell[0], append(shell.Shell[1:], inlineCommand)...)
+		ret = connection.LocalCommandBuilder(sh[0], append(sh[1:], inlineCommand)...)
 	case "ssh":
-		ret = connection.SshCommandBuilder(shell.Shell[0], append(shell.Shell[1:], inlineCommand)...)
+		ret = connection.SshCommandBuilder(sh[0], append(sh[1:], inlineCommand)...)
 	case "ssh+docker":
 		fallthrough
 	case "docker":
-		ret = connection.DockerCommandBuilder(shell.Shell[0], append(shell.Shell[1:], inlineCommand)...)
+		ret = connection.DockerCommandBuilder(sh[0], append(sh[1:], inlineCommand)...)
 	default:
 		panic(connection)
 	}
@@ -130,3 +132,12 @@ func (connection *Connection) GetType() string {
 
 	return connType
 }
+
+// Shell invocation for inline commands, the shell on remote hosts
+// and containers is unknown so the default isn't probed
+func shellArgv() []string {
+	if len(shell.Shell) > 0 {
+		return append([]string(nil), shell.Shell...)
+	}
+	return append([]string(nil), shell.ShellList["sh"]...)
+}
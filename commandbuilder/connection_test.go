@@ -3,6 +3,7 @@ package commandbuilder
 import (
 	"testing"
 	"github.com/webdevops/go-shell"
+	"github.com/webdevops/go-shell/shelltest"
 )
 
 func TestConnectionLocal(t *testing.T) {
@@ -72,3 +73,32 @@ func TestConnectionSshDocker(t *testing.T) {
 	}
 }
 
+
+func TestConnectionDockerCompose(t *testing.T) {
+	e := shelltest.NewExecutor(t)
+	e.Expect("docker-compose --no-ansi ps -q 'mysql'").Stdout("32ceb49d2958\n")
+
+	defer func(executor shell.Executor) { shell.DefaultRunner.Executor = executor }(shell.DefaultRunner.Executor)
+	shell.DefaultRunner.Executor = e
+
+	conn := Connection{}
+	conn.Docker.Set("compose:mysql")
+
+	cmd := shell.Cmd(conn.CommandBuilder("echo", "foobar")...)
+	if val := cmd.ToString(); val != "docker exec -i 32ceb49d2958 echo 'foobar'" {
+		t.Fatal("command builder not expected command:", val)
+	}
+}
+
+func TestConnectionDockerShell(t *testing.T) {
+	defer func(sh []string) { shell.Shell = sh }(shell.Shell)
+	shell.SetDefaultShell("sh")
+
+	conn := Connection{}
+	conn.Docker.Hostname = "containerid"
+
+	cmd := shell.Cmd(conn.RawShellCommandBuilder("echo", "foobar")...)
+	if val := cmd.ToString(); val != "docker exec -i containerid /bin/sh -o errexit -c 'echo foobar'" {
+		t.Fatal("command builder not expected command:", val)
+	}
+}
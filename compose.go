@@ -0,0 +1,230 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Precedence of shell operators, commands with a higher level are
+// grouped when used as operand of an operator with a lower level
+const (
+	levelSimple = iota
+	levelPipe
+	levelAndOr
+	levelList
+)
+
+// Run cmd if the command succeeds (cmd1 && cmd2)
+func (c *Command) And(cmd ...interface{}) *Command {
+	return c.combine("&&", levelAndOr, operandCmd(cmd...))
+}
+
+// Run cmd if the command fails (cmd1 || cmd2)
+func (c *Command) Or(cmd ...interface{}) *Command {
+	return c.combine("||", levelAndOr, operandCmd(cmd...))
+}
+
+// Run cmd after the command regardless of its exit status (cmd1; cmd2)
+func (c *Command) Then(cmd ...interface{}) *Command {
+	return c.combine(";", levelList, operandCmd(cmd...))
+}
+
+// Run the command in a subshell (( cmd ))
+func (c *Command) Subshell() *Command {
+	text, _ := c.composeString(false, false, true)
+	return c.compound("( "+text+" )", levelSimple)
+}
+
+// Redirect stdout of the command to the file at path, the file
+// is appended if append is true (cmd > path, cmd >> path)
+func (c *Command) RedirectStdout(path string, append bool) *Command {
+	if append {
+		return c.redirect(">>", Quote(path))
+	}
+	return c.redirect(">", Quote(path))
+}
+
+// Redirect stderr of the command to its stdout (cmd 2>&1)
+func (c *Command) RedirectStderrToStdout() *Command {
+	return c.redirect("2>&1")
+}
+
+// Run the command in background without waiting for it (cmd &)
+func (c *Command) Background() *Command {
+	text, precedence := c.composeString(false, false, true)
+	return c.compound(group(text, precedence, levelAndOr)+" &", levelList)
+}
+
+// Command used as operand, either a *Command or arguments for Cmd
+func operandCmd(cmd ...interface{}) *Command {
+	if len(cmd) == 1 {
+		if c, ok := cmd[0].(*Command); ok {
+			return c
+		}
+	}
+	return Cmd(cmd...)
+}
+
+// Precedence of the command including piped commands
+func (c *Command) precedence() int {
+	if c.in != nil {
+		return max(c.level, levelPipe)
+	}
+	return c.level
+}
+
+// Shell text of the command as operand of an operator with precedence
+// level, the command is grouped with braces if it binds weaker
+func (c *Command) operand(level int) string {
+	text := c.rawString()
+	if c.precedence() > level {
+		return "{ " + terminate(text) + "}"
+	}
+	return text
+}
+
+// Command is executed in exec mode (set for the command or its runner)
+func (c *Command) execMode() bool {
+	return c.exec || (!c.shell && c.getRunner().Exec)
+}
+
+// Group shell text with precedence for an operator with precedence
+// level, the text is grouped with braces if it binds weaker
+func group(text string, precedence int, level int) string {
+	if precedence > level {
+		return "{ " + terminate(text) + "}"
+	}
+	return text
+}
+
+// Shell text and precedence of the command including piped commands
+// for a compound command. Settings of piped commands are rendered into
+// the text, those of the command itself if inline is set (otherwise
+// only dir and env if env is set). Stdin of the first piped command
+// is kept if stdin is set.
+func (c *Command) composeString(inline bool, env bool, stdin bool) (string, int) {
+	// commands in exec mode are quoted so they run with the same arguments
+	text, level := c.shellCmd(c.execMode()), c.level
+	if inline {
+		c.checkInline(stdin && c.in == nil)
+	}
+	if inline || env {
+		text, level = c.inlineEnv(text, level)
+	}
+	if c.in == nil {
+		return text, level
+	}
+
+	upstream, upstreamLevel := c.in.composeString(true, true, stdin)
+	return group(upstream, upstreamLevel, levelPipe) + " | " + text, max(level, levelPipe)
+}
+
+// Panic for settings which can't be rendered into shell text
+func (c *Command) checkInline(stdin bool) {
+	var setting string
+	switch {
+	case c.stdin != nil && !stdin:
+		setting = "stdin"
+	case c.timeout > 0:
+		setting = "WithTimeout"
+	case c.gracePeriod > 0:
+		setting = "WithGracePeriod"
+	case c.stdoutLine != nil || c.stderrLine != nil:
+		setting = "line callback"
+	case c.retry != nil:
+		setting = "Retry"
+	case c.terminal != nil:
+		setting = "Pty"
+	}
+	if setting != "" {
+		panic(fmt.Sprintf("%s of %q can't be used in compound commands", setting, c.redact(c.shellCmd(false))))
+	}
+}
+
+// Render working directory and environment of the command into its
+// text (as subshell), ClearEnv can't be rendered and panics
+func (c *Command) inlineEnv(text string, level int) (string, int) {
+	if c.clearEnv {
+		panic(fmt.Sprintf("ClearEnv of %q can't be used in compound commands", c.redact(c.shellCmd(false))))
+	}
+	if c.dir == "" && len(c.env) == 0 {
+		return text, level
+	}
+
+	var prefix []string
+	if c.dir != "" {
+		prefix = append(prefix, "cd "+Quote(c.dir))
+	}
+	if len(c.env) > 0 {
+		vars := []string{"export"}
+		for _, env := range c.env {
+			name, value, _ := strings.Cut(env, "=")
+			vars = append(vars, name+"="+Quote(value))
+		}
+		prefix = append(prefix, strings.Join(vars, " "))
+	}
+	return "( " + strings.Join(append(prefix, text), " && ") + " )", levelSimple
+}
+
+// Terminate command text for a following command, commands in
+// background are already terminated by &
+func terminate(text string) string {
+	if strings.HasSuffix(text, "&") {
+		return text + " "
+	}
+	return text + "; "
+}
+
+// Combine command and cmd with a binary operator, && and || are left
+// associative, lists with ; are associative. Dir and env of both
+// operands are rendered into the text so they don't apply to the other
+// operand, further settings of cmd can't be used.
+func (c *Command) combine(op string, level int, cmd *Command) *Command {
+	left, leftLevel := c.composeString(false, true, true)
+	right, rightLevel := cmd.composeString(true, true, false)
+
+	var compound *Command
+	if op == ";" {
+		compound = c.compound(terminate(group(left, leftLevel, level))+group(right, rightLevel, level), level)
+	} else {
+		compound = c.compound(group(left, leftLevel, level)+" "+op+" "+group(right, rightLevel, level-1), level)
+	}
+	compound.dir = ""
+	compound.env = nil
+	return compound
+}
+
+// Copy of the command replaced by shell text, piped commands are
+// part of the text and settings of the command (eg. Env, Dir or
+// stdin of the first piped command) are kept. Settings of operands
+// and piped commands are part of the text (see composeString).
+func (c *Command) compound(text string, level int) *Command {
+	cmd := c.Clone()
+	if cmd.stdin == nil {
+		cmd.stdin = c.pipeline()[0].stdin
+	}
+	cmd.in = nil
+	cmd.exec = false
+	cmd.shell = true
+	cmd.args = []string{text}
+	cmd.level = level
+	return cmd
+}
+
+// Copy of the command with redirection, compound commands are grouped.
+// The command is executed by the shell, arguments of commands in exec
+// mode are quoted.
+func (c *Command) redirect(redirect ...string) *Command {
+	if c.level > levelSimple {
+		text, precedence := c.composeString(false, false, true)
+		return c.compound(group(text, precedence, levelSimple)+" "+strings.Join(redirect, " "), levelSimple)
+	}
+	cmd := c.Clone()
+	if c.execMode() {
+		cmd.args = QuoteValues(cmd.args...)
+		cmd.exec = false
+	}
+	cmd.shell = true
+	cmd.args = append(cmd.args, redirect...)
+	return cmd
+}
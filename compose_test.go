@@ -0,0 +1,136 @@
+package shell
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCompose(t *testing.T) {
+	a, b, c := Cmd("echo a"), Cmd("echo b"), Cmd("echo c")
+
+	for expected, cmd := range map[string]*Command{
+		"echo a && echo b":                Cmd("echo a").And("echo b"),
+		"echo a || echo b && echo c":      a.Or(b).And(c),
+		"echo a && { echo b || echo c; }": a.And(b.Or(c)),
+		"{ echo a; echo b; } && echo c":   a.Then(b).And(c),
+		"echo a; echo b; echo c":          a.Then(b.Then(c)),
+		"echo a && echo b &":              a.And(b).Background(),
+		"{ echo a; echo b; } &":           a.Then(b).Background(),
+		"echo a & echo b":                 a.Background().Then(b),
+		"{ echo a & } && echo b":          a.Background().And(b),
+		"( echo a; echo b ) | cat":        a.Then(b).Subshell().Pipe("cat"),
+		"{ echo a && echo b; } | cat":     a.And(b).Pipe("cat"),
+		"echo a | cat && echo b":          a.Pipe("cat").And(b),
+		"echo a > 'out file'":             a.RedirectStdout("out file", false),
+		"echo a | cat >> 'it'\\''s' 2>&1": a.Pipe("cat").RedirectStdout("it's", true).RedirectStderrToStdout(),
+		"{ echo a || echo b; } 2>&1":      a.Or(b).RedirectStderrToStdout(),
+	} {
+		if val := cmd.ToString(); val != expected {
+			t.Fatal("command not expected:", val)
+		}
+	}
+
+	if a.ToString() != "echo a" || b.ToString() != "echo b" {
+		t.Fatal("operands modified:", a.ToString(), b.ToString())
+	}
+}
+
+func TestComposeRun(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "out file")
+
+	p := Cmd("echo foo").RedirectStdout(file, false).
+		Then(Cmd("echo bar >&2").RedirectStderrToStdout().RedirectStdout(file, true)).
+		And("cat", Quote(file)).
+		Run()
+	if p.String() != "foo\nbar" {
+		t.Fatal("output not expected:", p.String())
+	}
+	if content, _ := os.ReadFile(file); string(content) != "foo\nbar\n" {
+		t.Fatal("file not expected:", string(content))
+	}
+
+	p = Cmd("false").Or("echo fallback").Then("echo done").Pipe("wc -l").Run()
+	if p.String() != "2" {
+		t.Fatal("output not expected:", p.String())
+	}
+
+	_, err := Cmd("true").And("false").Exec().RunE()
+	if !errors.Is(err, ErrShellSyntax) {
+		t.Fatal("error not expected:", err)
+	}
+}
+
+func TestComposeSettings(t *testing.T) {
+	dir := t.TempDir()
+
+	cmd := Cmd("pwd").Env("FOO", "foo").
+		And(Cmd("echo $FOO $BAR").Dir(dir).Env("FOO", "it's").Env("BAR", "bar")).
+		Then(Cmd("echo", "$FOO").Exec())
+	expected := "( export FOO='foo' && pwd ) && ( cd " + Quote(dir) + " && export FOO='it'\\''s' BAR='bar' && echo $FOO $BAR ); 'echo' '$FOO'"
+	if cmd.ToString() != expected {
+		t.Fatal("command not expected:", cmd.ToString())
+	}
+	p := cmd.Dir("/").Run()
+	if p.String() != "/\nit's bar\n$FOO" {
+		t.Fatal("output not expected:", p.String())
+	}
+
+	p = Cmd("cat").StdinString("foo").Env("FOO", "bar").Pipe("tr a-z A-Z").And("echo $FOO").Run()
+	if p.String() != "FOO" {
+		t.Fatal("output not expected:", p.String())
+	}
+
+	for _, operand := range []*Command{
+		Cmd("cat").StdinString("foo"),
+		Cmd("env").ClearEnv(),
+		Cmd("sleep 1").WithTimeout(time.Second),
+		Cmd("echo").Pipe("cat").WithTimeout(time.Second).Pipe("cat"),
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("panic expected:", operand.ToString())
+				}
+			}()
+			Cmd("true").And(operand)
+		}()
+	}
+}
+
+func TestComposeLeftSettings(t *testing.T) {
+	p := Cmd("pwd").Dir("/").And("pwd").Dir(os.TempDir()).Run()
+	if p.String() != "/\n"+os.TempDir() {
+		t.Fatal("output not expected:", p.String())
+	}
+
+	cmd := Cmd("echo", "$X").Env("X", "left").Then("echo", "right $X")
+	if cmd.ToString() != "( export X='left' && echo $X ); echo right $X" {
+		t.Fatal("command not expected:", cmd.ToString())
+	}
+	if p := cmd.Run(); p.String() != "left\nright" {
+		t.Fatal("output not expected:", p.String())
+	}
+}
+
+func TestComposeExec(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "out")
+
+	p := Cmd("echo", "hello $X").Exec().RedirectStdout(file, false).Run()
+	if content, _ := os.ReadFile(file); p.String() != "" || string(content) != "hello $X\n" {
+		t.Fatal("output not expected:", p.String(), string(content))
+	}
+
+	r := NewRunner()
+	r.Exec = true
+	p = r.Cmd("echo", "x").And("echo", "y").Run()
+	if p.String() != "x\ny" {
+		t.Fatal("output not expected:", p.String())
+	}
+	p = r.Cmd("sh", "-c", "echo x >&2").RedirectStderrToStdout().Run()
+	if p.String() != "x" {
+		t.Fatal("output not expected:", p.String())
+	}
+}
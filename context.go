@@ -0,0 +1,46 @@
+package shell
+
+import (
+	"context"
+	"syscall"
+	"time"
+)
+
+// Stop the started process (and its children) when ctx is done before
+// the process exits. The process receives SIGTERM first and SIGKILL if it
+// is still running after the grace period. The returned func stops
+// watching and reports if the process was stopped.
+func watchContext(ctx context.Context, handle Handle, grace time.Duration) func() bool {
+	if ctx.Done() == nil {
+		return func() bool { return false }
+	}
+
+	done := make(chan struct{})
+	killed := make(chan bool, 1)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+			killed <- false
+			return
+		}
+
+		handle.Signal(syscall.SIGTERM)
+
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			handle.Signal(syscall.SIGKILL)
+		case <-done:
+		}
+		killed <- true
+	}()
+
+	return func() bool {
+		close(done)
+		return <-killed
+	}
+}
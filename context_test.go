@@ -0,0 +1,85 @@
+package shell
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	defer func() {
+		p := recover().(*Process)
+		if !p.Canceled {
+			t.Fatal("process not canceled:", p.ExitStatus)
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Fatal("process not killed in time:", time.Since(start))
+		}
+	}()
+	Cmd("sleep 5; echo foobar").RunContext(ctx)
+}
+
+func TestCmdContextPipeCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	defer func() {
+		p := recover().(*Process)
+		if !p.Canceled {
+			t.Fatal("process not canceled:", p.ExitStatus)
+		}
+		if time.Since(start) > 2*time.Second {
+			t.Fatal("process not killed in time:", time.Since(start))
+		}
+	}()
+	CmdContext(ctx, "sleep 5 & sleep 5; wait").Pipe("cat").Run()
+}
+
+func TestCmdContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	defer func() {
+		p := recover().(*Process)
+		if !p.Canceled {
+			t.Fatal("process not canceled:", p.ExitStatus)
+		}
+	}()
+	CmdContext(ctx, "echo foobar").Run()
+}
+
+func TestWithTimeout(t *testing.T) {
+	var hooked *Process
+	defer func(fn func(c *Command, p *Process)) { TimeoutFunc = fn }(TimeoutFunc)
+	TimeoutFunc = func(c *Command, p *Process) { hooked = p }
+
+	defer func() {
+		p := recover().(*Process)
+		if !p.TimedOut || p.Canceled {
+			t.Fatal("process not timed out:", p.ExitStatus)
+		}
+		if hooked != p {
+			t.Fatal("timeout hook not called")
+		}
+	}()
+	Cmd("sleep 5").WithTimeout(100 * time.Millisecond).Run()
+}
+
+func TestWithTimeoutGracePeriod(t *testing.T) {
+	start := time.Now()
+	defer func() {
+		p := recover().(*Process)
+		if !p.TimedOut {
+			t.Fatal("process not timed out:", p.ExitStatus)
+		}
+		if elapsed := time.Since(start); elapsed < 300*time.Millisecond || elapsed > 3*time.Second {
+			t.Fatal("process not killed after grace period:", elapsed)
+		}
+	}()
+	Cmd("trap '' TERM; sleep 5").WithTimeout(100 * time.Millisecond).WithGracePeriod(300 * time.Millisecond).Run()
+}
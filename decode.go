@@ -0,0 +1,88 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+)
+
+// Decode JSON output of the command into v
+func (p *Process) JSON(v interface{}) error {
+	if err := json.Unmarshal(p.Stdout.Bytes(), v); err != nil {
+		return p.decodeError("json", err)
+	}
+	return nil
+}
+
+// Lines of the output without line endings, a trailing newline
+// doesn't create an empty line
+func (p *Process) Lines() []string {
+	var ret []string
+	for line := range p.IterLines() {
+		ret = append(ret, line)
+	}
+	return ret
+}
+
+// Whitespace separated fields of each non-empty line of the output
+// (eg. for ls or ps output)
+func (p *Process) Fields() [][]string {
+	var ret [][]string
+	for line := range p.IterLines() {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			ret = append(ret, fields)
+		}
+	}
+	return ret
+}
+
+// Parse output lines in key/value format separated by sep (eg. "="
+// for env output), lines are split at the first separator and empty
+// lines are ignored
+func (p *Process) KeyValues(sep string) (map[string]string, error) {
+	ret := map[string]string{}
+	lineNumber := 0
+	for line := range p.IterLines() {
+		lineNumber++
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key, value, found := strings.Cut(line, sep)
+		if !found {
+			err := fmt.Errorf("line %v: separator \"%s\" not found", lineNumber, sep)
+			return ret, p.decodeError("key/value", err)
+		}
+		ret[key] = value
+	}
+	return ret, nil
+}
+
+// Iterate over the lines of the output without copying the whole
+// output (see Lines)
+func (p *Process) IterLines() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		r := bufio.NewReader(bytes.NewReader(p.Stdout.Bytes()))
+		for {
+			line, err := r.ReadString('\n')
+			if len(line) > 0 && !yield(strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")) {
+				return
+			}
+			if err == io.EOF {
+				return
+			}
+		}
+	}
+}
+
+// Create decode error for output of the process
+func (p *Process) decodeError(format string, err error) error {
+	e := &DecodeError{Format: format, Err: err}
+	if p.Command != nil {
+		e.Command = p.Command.ToString()
+	}
+	return e
+}
@@ -0,0 +1,71 @@
+package shell
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestProcessJSON(t *testing.T) {
+	var v struct {
+		Name  string `json:"name"`
+		Ports []int  `json:"ports"`
+	}
+	p := Cmd(`echo '{"name": "mysql", "ports": [3306, 33060]}'`).Run()
+	if err := p.JSON(&v); err != nil || v.Name != "mysql" || len(v.Ports) != 2 {
+		t.Fatal("output not expected:", v, err)
+	}
+
+	p = Cmd("echo", "foobar").Run()
+	err := p.JSON(&v)
+	var decodeErr *DecodeError
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &decodeErr) || !errors.As(err, &syntaxErr) || decodeErr.Command != "echo foobar" {
+		t.Fatal("error not expected:", err)
+	}
+	if !strings.Contains(err.Error(), "echo foobar") {
+		t.Fatal("error message not expected:", err)
+	}
+}
+
+func TestProcessLines(t *testing.T) {
+	p := Cmd("printf 'foo\\nbar baz\\r\\n\\n  qux  quux\\n'").Run()
+
+	lines := p.Lines()
+	if len(lines) != 4 || lines[0] != "foo" || lines[1] != "bar baz" || lines[2] != "" {
+		t.Fatal("lines not expected:", lines)
+	}
+
+	fields := p.Fields()
+	if len(fields) != 3 || len(fields[1]) != 2 || fields[2][1] != "quux" {
+		t.Fatal("fields not expected:", fields)
+	}
+
+	var first []string
+	for line := range p.IterLines() {
+		first = append(first, line)
+		break
+	}
+	if len(first) != 1 || first[0] != "foo" {
+		t.Fatal("iterated lines not expected:", first)
+	}
+
+	if lines := Cmd("true").Run().Lines(); lines != nil {
+		t.Fatal("lines not expected:", lines)
+	}
+}
+
+func TestProcessKeyValues(t *testing.T) {
+	p := Cmd("printf 'FOO=bar\\n\\nURL=http://example.com/?a=b\\n'").Run()
+	values, err := p.KeyValues("=")
+	if err != nil || len(values) != 2 || values["URL"] != "http://example.com/?a=b" {
+		t.Fatal("values not expected:", values, err)
+	}
+
+	_, err = Cmd("printf 'FOO=bar\\nbarfoo\\n'").Run().KeyValues("=")
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || !strings.Contains(err.Error(), "line 2") {
+		t.Fatal("error not expected:", err)
+	}
+}
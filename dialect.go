@@ -0,0 +1,135 @@
+package shell
+
+import (
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Description of a shell used for command invocation
+type Dialect struct {
+	// Name of the dialect (eg. "bash")
+	Name string
+
+	// Shell invocation without options (eg. ["/bin/busybox", "sh"])
+	Command []string
+
+	// Shell supports the errexit, pipefail and nounset options (-o name)
+	Errexit  bool
+	Pipefail bool
+	Nounset  bool
+
+	// Expression expanding to the exit statuses of the last pipe
+	// (eg. "${PIPESTATUS[*]}" for bash), empty if not supported
+	PipeStatus string
+
+	// Pipefail support is detected on first use, eg. for /bin/sh
+	// which may be dash, ash or bash depending on the system
+	ProbePipefail bool
+
+	// Quote argument for the shell (defaults to POSIX quoting), used
+	// by Quote if the dialect is the default shell
+	Quote func(arg string) string
+
+	probe    sync.Once
+	pipefail bool
+	warn     sync.Once
+}
+
+var (
+	dialectsMu sync.RWMutex
+	dialects   = map[string]*Dialect{}
+
+	// Dialect selected by SetDefaultShell, nil for the sh dialect
+	// or shells of ShellList
+	defaultDialect *Dialect
+)
+
+func init() {
+	for _, d := range []*Dialect{
+		{Name: "sh", Command: []string{"/bin/sh"}, Errexit: true, Nounset: true, ProbePipefail: true},
+		{Name: "bash", Command: []string{"/bin/bash"}, Errexit: true, Pipefail: true, Nounset: true, PipeStatus: "${PIPESTATUS[*]}"},
+		{Name: "zsh", Command: []string{"/bin/zsh"}, Errexit: true, Pipefail: true, Nounset: true, PipeStatus: "${pipestatus[*]}"},
+		{Name: "dash", Command: []string{"/bin/dash"}, Errexit: true, Nounset: true, ProbePipefail: true},
+		{Name: "ash", Command: []string{"/bin/ash"}, Errexit: true, Pipefail: true, Nounset: true},
+		{Name: "busybox", Command: []string{"/bin/busybox", "sh"}, Errexit: true, Pipefail: true, Nounset: true},
+		{Name: "ksh", Command: []string{"/bin/ksh"}, Errexit: true, Pipefail: true, Nounset: true},
+		{Name: "fish", Command: []string{"/usr/bin/fish"}, PipeStatus: "$pipestatus", Quote: QuoteFish},
+	} {
+		RegisterDialect(d)
+	}
+}
+
+// Register dialect for SetDefaultShell, an existing dialect
+// with the same name is replaced
+func RegisterDialect(d *Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+	dialects[d.Name] = d
+}
+
+// Get registered dialect by name
+func GetDialect(name string) (*Dialect, bool) {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+	d, ok := dialects[name]
+	return d, ok
+}
+
+// Selected dialect of SetDefaultShell
+func getDefaultDialect() *Dialect {
+	dialectsMu.RLock()
+	defer dialectsMu.RUnlock()
+	return defaultDialect
+}
+
+func setDefaultDialect(d *Dialect) {
+	dialectsMu.Lock()
+	defer dialectsMu.Unlock()
+	defaultDialect = d
+}
+
+// Check if the shell supports pipefail, probing the shell if needed.
+// Failures of inline pipes are not detected by shells without pipefail.
+func (d *Dialect) SupportsPipefail() bool {
+	if d.Pipefail || !d.ProbePipefail {
+		return d.Pipefail
+	}
+	d.probe.Do(func() {
+		args := append(append([]string(nil), d.Command[1:]...), "-o", "pipefail", "-c", "true")
+		d.pipefail = exec.Command(d.Command[0], args...).Run() == nil
+	})
+	return d.pipefail
+}
+
+// Shell invocation for a command string with errexit and pipefail
+// enabled if supported (eg. ["/bin/bash", "-o", "errexit", "-o", "pipefail", "-c"])
+func (d *Dialect) Argv() []string {
+	return d.argv(d.SupportsPipefail())
+}
+
+// Shell invocation with pipefail enabled if requested
+func (d *Dialect) argv(pipefail bool) []string {
+	argv := append([]string(nil), d.Command...)
+	if d.Errexit {
+		argv = append(argv, "-o", "errexit")
+	}
+	if pipefail {
+		argv = append(argv, "-o", "pipefail")
+	}
+	return append(argv, "-c")
+}
+
+// Quote argument for the shell
+func (d *Dialect) QuoteArg(arg string) string {
+	if d.Quote != nil {
+		return d.Quote(arg)
+	}
+	return quotePosix(arg)
+}
+
+// Quote argument for fish, which supports escaping inside single quotes
+func QuoteFish(arg string) string {
+	arg = strings.Replace(arg, "\\", "\\\\", -1)
+	return "'" + strings.Replace(arg, "'", "\\'", -1) + "'"
+}
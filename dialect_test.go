@@ -0,0 +1,179 @@
+package shell
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestDialectArgv(t *testing.T) {
+	bash, ok := GetDialect("bash")
+	if !ok {
+		t.Fatal("bash dialect not registered")
+	}
+	if argv := strings.Join(bash.Argv(), " "); argv != "/bin/bash -o errexit -o pipefail -c" {
+		t.Fatal("argv not expected:", argv)
+	}
+
+	busybox, _ := GetDialect("busybox")
+	if argv := strings.Join(busybox.Argv(), " "); argv != "/bin/busybox sh -o errexit -o pipefail -c" {
+		t.Fatal("argv not expected:", argv)
+	}
+
+	fish, _ := GetDialect("fish")
+	if argv := strings.Join(fish.Argv(), " "); argv != "/usr/bin/fish -c" {
+		t.Fatal("argv not expected:", argv)
+	}
+	if val := fish.QuoteArg(`it's a \ test`); val != `'it\'s a \\ test'` {
+		t.Fatal("quoted argument not expected:", val)
+	}
+	if val := bash.QuoteArg("it's"); val != `'it'\''s'` {
+		t.Fatal("quoted argument not expected:", val)
+	}
+}
+
+func TestDialectProbe(t *testing.T) {
+	sh, _ := GetDialect("sh")
+	supported := exec.Command("/bin/sh", "-o", "pipefail", "-c", "true").Run() == nil
+	if sh.SupportsPipefail() != supported {
+		t.Fatal("pipefail support not expected:", supported)
+	}
+
+	r := NewRunner()
+	r.Panic = false
+	p := r.Cmd("false | true").Run()
+	if supported && p.ExitStatus == 0 {
+		t.Fatal("pipefail not enabled:", p.ExitStatus)
+	}
+}
+
+func TestDialectPipefailMissing(t *testing.T) {
+	RegisterDialect(&Dialect{Name: "test-nopipefail", Command: []string{"/bin/sh"}, Errexit: true})
+	d, _ := GetDialect("test-nopipefail")
+
+	defer func(shell []string) {
+		Shell = shell
+		setDefaultDialect(nil)
+	}(Shell)
+	SetDefaultShell("test-nopipefail")
+
+	var buf bytes.Buffer
+	r := NewRunner()
+	r.Panic = false
+	r.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+	r.Run("true")
+	r.Run("true")
+	if out := buf.String(); strings.Count(out, "does not support pipefail") != 1 || !strings.Contains(out, "level=WARN") {
+		t.Fatal("pipefail warning not expected:", out)
+	}
+
+	r.RequirePipefail = true
+	p := r.Run("true")
+	if !errors.Is(p.Err, ErrPipefailNotSupported) || p.ExitStatus == 0 {
+		t.Fatal("pipefail error not expected:", p.ExitStatus, p.Err)
+	}
+
+	if d.SupportsPipefail() || d.Nounset || d.PipeStatus != "" {
+		t.Fatal("dialect not expected:", d)
+	}
+}
+
+func TestDialectNounset(t *testing.T) {
+	for _, name := range []string{"sh", "bash", "zsh", "dash", "ash", "busybox", "ksh", "fish"} {
+		d, _ := GetDialect(name)
+		if _, err := os.Stat(d.Command[0]); err != nil {
+			continue
+		}
+		if !d.Nounset {
+			// fish reports unset variables as empty
+			if name != "fish" {
+				t.Fatal("nounset not expected:", name)
+			}
+			continue
+		}
+		args := append(append([]string(nil), d.Command[1:]...), "-o", "nounset", "-c", "echo $GO_SHELL_UNSET")
+		if exec.Command(d.Command[0], args...).Run() == nil {
+			t.Fatal("nounset not enabled:", name)
+		}
+	}
+}
+
+func TestDialectPipeStatus(t *testing.T) {
+	for _, name := range []string{"sh", "bash", "zsh", "dash", "ash", "busybox", "ksh", "fish"} {
+		d, _ := GetDialect(name)
+		if d.PipeStatus == "" {
+			continue
+		}
+		if _, err := os.Stat(d.Command[0]); err != nil {
+			continue
+		}
+		args := append(append([]string(nil), d.Command[1:]...), "-c", "false | true; echo "+d.PipeStatus)
+		out, err := exec.Command(d.Command[0], args...).Output()
+		if err != nil || strings.TrimSpace(string(out)) != "1 0" {
+			t.Fatal("pipe status not expected:", name, string(out), err)
+		}
+	}
+
+	if d, _ := GetDialect("bash"); d.PipeStatus != "${PIPESTATUS[*]}" {
+		t.Fatal("bash pipe status not expected:", d.PipeStatus)
+	}
+}
+
+func TestDefaultShell(t *testing.T) {
+	if argv := strings.Join(Shell, " "); argv != "/bin/sh -o errexit -c" {
+		t.Fatal("shell not expected:", argv)
+	}
+
+	defer func(shell []string) {
+		Shell = shell
+		setDefaultDialect(nil)
+	}(Shell)
+
+	// Shell is used for remote shells, it must not be probed
+	SetDefaultShell("dash")
+	if argv := strings.Join(Shell, " "); argv != "/bin/dash -o errexit -c" {
+		t.Fatal("shell not expected:", argv)
+	}
+	dash, _ := GetDialect("dash")
+	if r := defaultRunner(); r.Shell != nil {
+		t.Fatal("runner shell not expected:", r.Shell)
+	} else if shell, err := r.shell(); err != nil || strings.Join(shell, " ") != strings.Join(dash.Argv(), " ") {
+		t.Fatal("runner shell not expected:", shell, err)
+	}
+
+	SetDefaultShell("fish")
+	if val := Quote(`it's a \ test`); val != `'it\'s a \\ test'` {
+		t.Fatal("quoted argument not expected:", val)
+	}
+	SetDefaultShell("sh")
+	if val := Quote("it's"); val != `'it'\''s'` {
+		t.Fatal("quoted argument not expected:", val)
+	}
+}
+
+func TestRegisterDialect(t *testing.T) {
+	RegisterDialect(&Dialect{Name: "test-bash", Command: []string{"/bin/bash", "--norc"}, Errexit: true})
+
+	defer func(shell []string) {
+		Shell = shell
+		setDefaultDialect(nil)
+	}(Shell)
+	SetDefaultShell("test-bash")
+	if argv := strings.Join(Shell, " "); argv != "/bin/bash --norc -o errexit -c" {
+		t.Fatal("shell not expected:", argv)
+	}
+	if output := Run("echo $BASH_VERSION").String(); output == "" {
+		t.Fatal("command not executed with bash")
+	}
+
+	defer func() {
+		if r := recover(); r != "Shell unknown is not supported" {
+			t.Fatal("panic not expected:", r)
+		}
+	}()
+	SetDefaultShell("unknown")
+}
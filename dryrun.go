@@ -0,0 +1,47 @@
+package shell
+
+import (
+	"strings"
+	"sync"
+)
+
+// Plan of commands recorded by a dry-run runner, commands are not
+// executed and return a successful process with fake stdout
+type Plan struct {
+	// Fake stdout for a planned command (optional)
+	Stdout func(c *Command) string
+
+	mu       sync.Mutex
+	commands []string
+}
+
+// Record command and return its fake stdout
+func (p *Plan) record(c *Command) string {
+	p.mu.Lock()
+	p.commands = append(p.commands, c.ToString())
+	p.mu.Unlock()
+
+	if p.Stdout != nil {
+		return p.Stdout(c)
+	}
+	return ""
+}
+
+// List of recorded commands (including piped commands)
+func (p *Plan) Commands() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.commands...)
+}
+
+// Clear recorded commands
+func (p *Plan) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.commands = nil
+}
+
+// Recorded commands, one per line
+func (p *Plan) String() string {
+	return strings.Join(p.Commands(), "\n")
+}
@@ -0,0 +1,34 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	r := NewRunner()
+	r.DryRun = &Plan{
+		Stdout: func(c *Command) string {
+			return "fake " + c.ToString()
+		},
+	}
+
+	p := r.Cmd("rm -rf /go-shell-dry-run").Pipe("exit 1").Run()
+	if p.String() != "fake rm -rf /go-shell-dry-run | exit 1" {
+		t.Fatal("output not expected:", p.String())
+	}
+	if p.ExitStatus != 0 || !reflect.DeepEqual(p.PipeStatus, []int{0, 0}) {
+		t.Fatal("status not expected:", p.ExitStatus, p.PipeStatus)
+	}
+
+	rp, err := r.Cmd("sleep", "5").Start()
+	if err != nil || rp.Pid() != 0 {
+		t.Fatal("background process not expected:", rp.Pid(), err)
+	}
+	rp.Wait()
+
+	expected := []string{"rm -rf /go-shell-dry-run | exit 1", "sleep 5"}
+	if !reflect.DeepEqual(r.DryRun.Commands(), expected) {
+		t.Fatal("plan not expected:", r.DryRun.Commands())
+	}
+}
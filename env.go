@@ -0,0 +1,50 @@
+package shell
+
+import (
+	"os"
+	"sort"
+)
+
+// Set environment variable for the command
+func (c *Command) Env(name, value string) *Command {
+	c.env = append(c.env, name+"="+value)
+	return c
+}
+
+// Set multiple environment variables for the command
+func (c *Command) EnvMap(vars map[string]string) *Command {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		c.Env(name, vars[name])
+	}
+	return c
+}
+
+// Don't inherit the environment of the current process,
+// only variables set for the command are passed
+func (c *Command) ClearEnv() *Command {
+	c.clearEnv = true
+	return c
+}
+
+// Set working directory of the command
+func (c *Command) Dir(path string) *Command {
+	c.dir = path
+	return c
+}
+
+// Environment of the command, nil if the current environment is used
+func (c *Command) environ() []string {
+	if c.clearEnv {
+		return append([]string{}, c.env...)
+	}
+	if len(c.env) == 0 {
+		return nil
+	}
+	return append(os.Environ(), c.env...)
+}
@@ -0,0 +1,36 @@
+package shell
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEnv(t *testing.T) {
+	p := Cmd("echo $FOO-$BAR").Env("FOO", "foo").EnvMap(map[string]string{"BAR": "bar"}).Run()
+	if p.String() != "foo-bar" {
+		t.Fatal("output not expected:", p.String())
+	}
+
+	p = Cmd("echo $FOO").Env("FOO", "foo").Env("FOO", "bar").Run()
+	if p.String() != "bar" {
+		t.Fatal("output not expected:", p.String())
+	}
+}
+
+func TestClearEnv(t *testing.T) {
+	os.Setenv("GO_SHELL_TEST", "foobar")
+	defer os.Unsetenv("GO_SHELL_TEST")
+
+	p := Cmd("echo \"$GO_SHELL_TEST\"").ClearEnv().Run()
+	if p.String() != "" {
+		t.Fatal("output not expected:", p.String())
+	}
+}
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	p := Cmd("echo $FOO").Env("FOO", "foo").Pipe("cat; pwd").Dir(dir).Run()
+	if p.String() != "foo\n"+dir {
+		t.Fatal("output not expected:", p.String())
+	}
+}
@@ -0,0 +1,137 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+var (
+	// Command exited with status 127
+	ErrCommandNotFound = errors.New("command not found")
+
+	// Command exited with status 126
+	ErrNotExecutable = errors.New("command not executable")
+
+	// Command was stopped because its timeout was reached
+	ErrTimeout = errors.New("command timed out")
+
+	// Command was stopped because its context was done
+	ErrCanceled = errors.New("command canceled")
+
+	// Command uses shell syntax which is not supported in exec mode
+	ErrShellSyntax = errors.New("shell syntax not supported in exec mode")
+
+	// Shell does not support pipefail but the runner requires it
+	ErrPipefailNotSupported = errors.New("pipefail not supported by shell")
+
+	// Pseudo-terminals are not supported on this platform or for the command
+	ErrPtyNotSupported = errors.New("pty not supported")
+
+	// Number of stderr lines kept in ExitError
+	ExitErrorStderrLines = 10
+)
+
+// Error of a failed command execution
+type ExitError struct {
+	// Exit status of the command (-1 if terminated by signal)
+	ExitStatus int
+
+	// Signal which terminated the command
+	Signal syscall.Signal
+
+	// Last lines of stderr
+	Stderr string
+
+	// Human readable command
+	Command string
+
+	// Command was stopped because its timeout was reached
+	TimedOut bool
+
+	// Command was stopped because its context was done
+	Canceled bool
+
+	// Error which prevented the command from running
+	Err error
+}
+
+func (e *ExitError) Error() string {
+	switch {
+	case e.TimedOut:
+		return fmt.Sprintf("[%v] %s\n", e.ExitStatus, ErrTimeout)
+	case e.Canceled:
+		return fmt.Sprintf("[%v] %s\n", e.ExitStatus, ErrCanceled)
+	}
+
+	errlines := strings.Split(e.Stderr, "\n")
+	if len(errlines) < 2 {
+		return fmt.Sprintf("[%v] %s\n", e.ExitStatus, errlines[0])
+	}
+	return fmt.Sprintf("[%v] %s\n", e.ExitStatus, errlines[len(errlines)-2])
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// Match sentinel errors (eg. ErrCommandNotFound) using errors.Is
+func (e *ExitError) Is(target error) bool {
+	switch target {
+	case ErrCommandNotFound:
+		return e.ExitStatus == 127
+	case ErrNotExecutable:
+		return e.ExitStatus == 126
+	case ErrTimeout:
+		return e.TimedOut
+	case ErrCanceled:
+		return e.Canceled
+	}
+	return false
+}
+
+// Error of decoding the output of a command
+type DecodeError struct {
+	// Human readable command
+	Command string
+
+	// Format of the output (eg. "json")
+	Format string
+
+	// Error of the decoder
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("cannot decode %s output of command \"%s\": %v", e.Format, e.Command, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Map errors of starting a command to the sentinel errors
+func startError(err error) error {
+	if errors.Is(err, exec.ErrNotFound) {
+		return fmt.Errorf("%w: %w", ErrCommandNotFound, err)
+	}
+	return err
+}
+
+// Last lines of stderr output, including the trailing newline
+func stderrTail(stderr string, lines int) string {
+	if lines <= 0 {
+		return stderr
+	}
+
+	pos := len(strings.TrimSuffix(stderr, "\n"))
+	for i := 0; i < lines; i++ {
+		pos = strings.LastIndex(stderr[:pos], "\n")
+		if pos == -1 {
+			return stderr
+		}
+	}
+	return stderr[pos+1:]
+}
@@ -0,0 +1,55 @@
+package shell
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRunE(t *testing.T) {
+	p, err := Cmd("echo foobar >&2; exit 3").RunE()
+	if p.ExitStatus != 3 {
+		t.Fatal("status not expected:", p.ExitStatus)
+	}
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatal("error not expected:", err)
+	}
+	if exitErr.ExitStatus != 3 || exitErr.Stderr != "foobar\n" {
+		t.Fatal("exit error not expected:", exitErr.ExitStatus, exitErr.Stderr)
+	}
+	if exitErr.Command != "echo foobar >&2; exit 3" {
+		t.Fatal("command not expected:", exitErr.Command)
+	}
+	if err.Error() != "[3] foobar\n" {
+		t.Fatal("output not expected:", err)
+	}
+
+	p, err = Cmd("echo", "foobar").RunE()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if p.String() != "foobar" {
+		t.Fatal("output not expected:", p.String())
+	}
+}
+
+func TestRunESentinel(t *testing.T) {
+	_, err := Cmd("go-shell-command-not-found").RunE()
+	if !errors.Is(err, ErrCommandNotFound) {
+		t.Fatal("error not expected:", err)
+	}
+
+	_, err = Cmd("exit 126").RunE()
+	if !errors.Is(err, ErrNotExecutable) || errors.Is(err, ErrCommandNotFound) {
+		t.Fatal("error not expected:", err)
+	}
+}
+
+func TestStderrTail(t *testing.T) {
+	tail := stderrTail(strings.Repeat("foobar\n", 5)+"last\n", 2)
+	if tail != "foobar\nlast\n" {
+		t.Fatal("output not expected:", tail)
+	}
+}
@@ -0,0 +1,58 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Characters which indicate shell syntax in the executable name
+// (eg. a whole command line passed as single string)
+const execShellChars = " \t\n|&;<>()!$`\\\"'*?#~={}"
+
+var (
+	// Shell operators which can't be used as argument in exec mode
+	execShellOperators = map[string]bool{
+		"|": true, "||": true, "|&": true, "&": true, "&&": true,
+		";": true, ";;": true, "(": true, ")": true, "!": true,
+	}
+
+	// Redirections like ">", "2>", "2>&1" or "<<"
+	execShellRedirect = regexp.MustCompile(`^([0-9]*|&)(<|<<|<<<|>|>>|<&|>&)([0-9]+|-)?$`)
+
+	// Arguments already quoted for the shell (see Quote)
+	execShellQuoted = regexp.MustCompile(`^'([^']|'\\'')*'$`)
+)
+
+// Execute the command directly without shell, arguments are passed
+// as they are (without quoting or expansion). Each argument has to be
+// a single word, pipes have to be created using Pipe(). Shell operators,
+// redirections and quoted arguments (eg. output of commandbuilder)
+// fail with ErrShellSyntax.
+func (c *Command) Exec() *Command {
+	c.exec = true
+	return c
+}
+
+// Arguments for direct execution, fails if shell syntax is used
+func (c *Command) argv() ([]string, error) {
+	if len(c.args) == 0 {
+		return nil, errors.New("empty command")
+	}
+
+	if c.args[0] == "" || c.args[0] == "[[" || strings.ContainsAny(c.args[0], execShellChars) {
+		return nil, fmt.Errorf("%w: command %q must be a single executable, pass arguments separately", ErrShellSyntax, c.args[0])
+	}
+
+	for _, arg := range c.args[1:] {
+		if execShellOperators[arg] || execShellRedirect.MatchString(arg) {
+			return nil, fmt.Errorf("%w: operator %q in %q", ErrShellSyntax, arg, c.redact(c.shellCmd(false)))
+		}
+		if execShellQuoted.MatchString(arg) {
+			return nil, fmt.Errorf("%w: quoted argument %s in %q, pass arguments unquoted", ErrShellSyntax, c.redact(arg), c.redact(c.shellCmd(false)))
+		}
+	}
+
+	return append([]string(nil), c.args...), nil
+}
@@ -0,0 +1,52 @@
+package shell
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExec(t *testing.T) {
+	p := Cmd("echo", "$HOME", "foo bar", "it's").Exec().Run()
+	if p.String() != "$HOME foo bar it's" {
+		t.Fatal("output not expected:", p.String())
+	}
+
+	r := NewRunner()
+	r.Exec = true
+	p = r.Cmd("echo", "foo;bar").Pipe("tr", "a-z", "A-Z").Run()
+	if p.String() != "FOO;BAR" {
+		t.Fatal("output not expected:", p.String())
+	}
+
+	p = Cmd("[", "foo", "=", "foo", "]").Exec().Run()
+	if p.ExitStatus != 0 {
+		t.Fatal("exit status not expected:", p.ExitStatus)
+	}
+}
+
+func TestExecShellSyntax(t *testing.T) {
+	for _, cmd := range []*Command{
+		Cmd("echo foobar | wc -c"),
+		Cmd("echo", "foobar", "|", "wc", "-c"),
+		Cmd("echo", "foobar", "2>&1"),
+		Cmd("echo", "foobar", ">>"),
+		Cmd("echo", "(", "foobar", ")"),
+		Cmd("echo", "'foobar'"),
+		Cmd("echo", Quote("it's")),
+		Cmd("FOO=bar", "env"),
+		Cmd("echo;", "foobar"),
+		Cmd("!", "false"),
+		Cmd("[[", "-n", "foobar", "]]"),
+	} {
+		if _, err := cmd.Exec().RunE(); !errors.Is(err, ErrShellSyntax) {
+			t.Fatal("error not expected:", cmd.ToString(), err)
+		}
+	}
+}
+
+func TestExecNotFound(t *testing.T) {
+	_, err := Cmd("go-shell-command-not-found").Exec().RunE()
+	if !errors.Is(err, ErrCommandNotFound) {
+		t.Fatal("error not expected:", err)
+	}
+}
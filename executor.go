@@ -0,0 +1,196 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Executor starts the processes of commands, the default executor starts
+// real processes. Custom executors can be used for testing (see shelltest).
+type Executor interface {
+	// Start process for a single command. Stdio of the spawn has to be
+	// served until the process is finished, ClosePipes of the spawn has
+	// to be called as soon as the executor doesn't use its pipes anymore.
+	Start(s *Spawn) (Handle, error)
+}
+
+// Handle of a started process
+type Handle interface {
+	// Process id
+	Pid() int
+
+	// Send signal to the process and its children
+	Signal(sig syscall.Signal) error
+
+	// Wait for the process, error is only returned if waiting failed
+	Wait() (ExitState, error)
+}
+
+// State of a finished process
+type ExitState struct {
+	// Exit status of the process (-1 if terminated by signal)
+	ExitStatus int
+
+	// Signal which terminated the process
+	Signal syscall.Signal
+
+	// Process dumped core when it was terminated by the signal
+	CoreDump bool
+
+	// Resource usage of the process
+	Usage
+}
+
+// Resource usage of finished processes
+type Usage struct {
+	// Wall-clock duration
+	Duration time.Duration
+
+	// CPU time spent in user mode
+	UserTime time.Duration
+
+	// CPU time spent in kernel mode
+	SystemTime time.Duration
+
+	// Maximum resident set size in bytes (0 if not supported by the OS)
+	MaxRSS int64
+}
+
+func (u Usage) String() string {
+	return fmt.Sprintf("%v wall, %v user, %v system, %v KB max rss",
+		u.Duration.Round(time.Millisecond), u.UserTime, u.SystemTime, u.MaxRSS/1024)
+}
+
+// Add CPU times and memory of other usage, durations of concurrent
+// processes overlap so the longer one is kept
+func (u *Usage) add(other Usage) {
+	u.Duration = max(u.Duration, other.Duration)
+	u.UserTime += other.UserTime
+	u.SystemTime += other.SystemTime
+	u.MaxRSS += other.MaxRSS
+}
+
+// Process to be started for a single (piped) command
+type Spawn struct {
+	// Command of the process
+	Command *Command
+
+	// Program and arguments (eg. shell invocation)
+	Args []string
+
+	// Environment (nil if current environment is used)
+	Env []string
+
+	// Working directory
+	Dir string
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Stdio is a pseudo-terminal, the process has to be started in a new
+	// session with the terminal as controlling terminal
+	Terminal bool
+
+	line  string
+	mu    sync.Mutex
+	pipes []io.Closer
+}
+
+// Default executor starting real processes
+var DefaultExecutor Executor = execExecutor{}
+
+// Human readable command line of the single command
+func (s *Spawn) String() string {
+	return s.line
+}
+
+// Close the parent copies of the pipes connected to the process
+func (s *Spawn) ClosePipes() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, closer := range s.pipes {
+		closer.Close()
+	}
+	s.pipes = nil
+}
+
+// Add pipe end which is closed by ClosePipes
+func (s *Spawn) addPipe(closer io.Closer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pipes = append(s.pipes, closer)
+}
+
+type execExecutor struct{}
+
+func (execExecutor) Start(s *Spawn) (Handle, error) {
+	cmd := exec.Command(s.Args[0], s.Args[1:]...)
+	cmd.Env = s.Env
+	cmd.Dir = s.Dir
+	cmd.Stdin = s.Stdin
+	cmd.Stdout = s.Stdout
+	cmd.Stderr = s.Stderr
+	if s.Terminal {
+		setControllingTerminal(cmd)
+	} else {
+		setProcessGroup(cmd)
+	}
+
+	started := time.Now()
+	err := cmd.Start()
+	s.ClosePipes()
+	if err != nil {
+		return nil, err
+	}
+	return &execHandle{cmd: cmd, started: started}, nil
+}
+
+// Handle of a real process
+type execHandle struct {
+	cmd     *exec.Cmd
+	started time.Time
+}
+
+func (h *execHandle) Pid() int {
+	return h.cmd.Process.Pid
+}
+
+func (h *execHandle) Signal(sig syscall.Signal) error {
+	return signalProcessGroup(h.cmd, sig)
+}
+
+func (h *execHandle) Wait() (ExitState, error) {
+	err := h.cmd.Wait()
+	ps := h.cmd.ProcessState
+	if ps == nil {
+		return ExitState{ExitStatus: -1}, err
+	}
+
+	state := ExitState{
+		ExitStatus: ps.ExitCode(),
+		Usage: Usage{
+			Duration:   time.Since(h.started),
+			UserTime:   ps.UserTime(),
+			SystemTime: ps.SystemTime(),
+			MaxRSS:     maxRSS(ps),
+		},
+	}
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		state.ExitStatus = -1
+		return state, err
+	}
+
+	if stat, ok := ps.Sys().(syscall.WaitStatus); ok {
+		state.ExitStatus = stat.ExitStatus()
+		if stat.Signaled() {
+			state.Signal = stat.Signal()
+			state.CoreDump = stat.CoreDump()
+		}
+	}
+	return state, nil
+}
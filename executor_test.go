@@ -0,0 +1,41 @@
+package shell
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestProcessUsage(t *testing.T) {
+	p := Cmd("sleep 0.2; i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done").Pipe("cat").Run()
+
+	if len(p.Stages) != 2 {
+		t.Fatal("stages not expected:", p.Stages)
+	}
+	if p.Usage.Duration < 200*time.Millisecond || p.Stages[0].Duration < 200*time.Millisecond {
+		t.Fatal("duration not expected:", p.Usage.Duration, p.Stages[0].Duration)
+	}
+	if p.Usage.UserTime+p.Usage.SystemTime <= 0 || p.Usage.UserTime != p.Stages[0].UserTime+p.Stages[1].UserTime {
+		t.Fatal("cpu time not expected:", p.Usage, p.Stages)
+	}
+	if p.Usage.MaxRSS <= 0 {
+		t.Fatal("max rss not expected:", p.Usage.MaxRSS)
+	}
+	if debug := p.Debug(); !strings.Contains(debug, "USAGE:") || !strings.Contains(debug, "STAGE 2:") {
+		t.Fatal("debug output not expected:", debug)
+	}
+}
+
+func TestProcessSignal(t *testing.T) {
+	r := NewRunner()
+	r.Panic = false
+
+	p := r.Cmd("kill -KILL $$").Run()
+	if p.Signal != syscall.SIGKILL || p.CoreDump || p.Stages[0].Signal != syscall.SIGKILL {
+		t.Fatal("signal not expected:", p.Signal, p.CoreDump)
+	}
+	if !strings.Contains(p.Debug(), "SIGNAL:    killed") {
+		t.Fatal("debug output not expected:", p.Debug())
+	}
+}
@@ -0,0 +1,63 @@
+package shell
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Stage of a piped command in the execution log
+type logStage struct {
+	Command    string `json:"command"`
+	Pid        int    `json:"pid"`
+	ExitStatus int    `json:"exit_status"`
+}
+
+// Create logger writing one JSON record per line to w
+func NewJSONLogger(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, nil))
+}
+
+// Write execution record of the pipeline to the runner logger
+func (pl *pipeline) log(err error) {
+	logger := pl.runner.Logger
+	if logger == nil {
+		return
+	}
+
+	p := pl.process
+	stages := make([]logStage, len(pl.stages))
+	for i, s := range pl.stages {
+		stages[i] = logStage{
+			Command:    pl.command.redact(s.spawn.String()),
+			Pid:        s.handle.Pid(),
+			ExitStatus: p.PipeStatus[i],
+		}
+	}
+
+	attrs := []slog.Attr{
+		slog.String("command", pl.command.ToString()),
+		slog.Any("stages", stages),
+		slog.Int("pid", stages[len(stages)-1].Pid),
+		slog.Time("start", pl.started),
+		slog.Duration("duration", time.Since(pl.started)),
+		slog.Duration("user_time", p.Usage.UserTime),
+		slog.Duration("system_time", p.Usage.SystemTime),
+		slog.Int64("max_rss", p.Usage.MaxRSS),
+		slog.Int("exit_status", p.ExitStatus),
+		slog.Int("signal", int(p.Signal)),
+		slog.Int("stdout_bytes", pl.stdout.Len()),
+		slog.Int("stderr_bytes", pl.stderr.Len()),
+	}
+
+	level := slog.LevelInfo
+	msg := "command executed"
+	if err != nil {
+		level = slog.LevelError
+		msg = "command failed"
+		attrs = append(attrs, slog.String("error", pl.command.redact(strings.TrimSpace(err.Error()))))
+	}
+
+	logger.LogAttrs(pl.ctx, level, msg, attrs...)
+}
@@ -0,0 +1,48 @@
+package shell
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRunner()
+	r.Panic = false
+	// explicit shell, no pipefail warning of the probed /bin/sh
+	r.Shell = Shell
+	r.Logger = NewJSONLogger(&buf)
+
+	r.Cmd("echo foobar; echo barfoo >&2").Pipe("cat").Run()
+	r.Cmd("exit 3").Run()
+
+	var records []map[string]interface{}
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var record map[string]interface{}
+		if err := dec.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatal("records not expected:", records)
+	}
+
+	record := records[0]
+	if record["msg"] != "command executed" || record["command"] != "echo foobar; echo barfoo >&2 | cat" {
+		t.Fatal("record not expected:", record)
+	}
+	if record["stdout_bytes"] != float64(7) || record["stderr_bytes"] != float64(7) || record["pid"].(float64) <= 0 {
+		t.Fatal("record not expected:", record)
+	}
+	if stages := record["stages"].([]interface{}); len(stages) != 2 {
+		t.Fatal("stages not expected:", stages)
+	}
+
+	record = records[1]
+	if record["level"] != "ERROR" || record["exit_status"] != float64(3) {
+		t.Fatal("record not expected:", record)
+	}
+}
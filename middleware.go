@@ -0,0 +1,65 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+)
+
+// Handler executing a command, returns the process and an error
+// for failed executions (see RunE)
+type Handler func(ctx context.Context, c *Command) (*Process, error)
+
+// Middleware wraps the execution of commands. It can inspect or rewrite
+// the command before calling next, skip next to short-circuit the
+// execution or observe the resulting process afterwards. Processes of
+// short-circuited executions get empty output buffers if they have none.
+type Middleware func(next Handler) Handler
+
+// Register middleware for all commands executed by this runner,
+// middleware registered first is called first
+func (r *Runner) Use(middleware ...Middleware) {
+	r.middleware = append(r.middleware, middleware...)
+}
+
+// Register middleware for commands executed by the DefaultRunner
+func Use(middleware ...Middleware) {
+	DefaultRunner.Use(middleware...)
+}
+
+// Wrap handler with the middleware chain of the runner
+func (r *Runner) handler(h Handler) Handler {
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		h = r.middleware[i](h)
+	}
+	return h
+}
+
+// Fill in fields of a process returned by middleware, processes of
+// short-circuited executions may lack the command or output buffers
+func completeProcess(c *Command, p *Process) *Process {
+	if p == nil {
+		p = &Process{}
+	}
+	if p.Command == nil {
+		p.Command = c
+	}
+	if p.Stdout == nil {
+		p.Stdout = new(bytes.Buffer)
+	}
+	if p.Stderr == nil {
+		p.Stderr = new(bytes.Buffer)
+	}
+	return p
+}
+
+// Arguments of the command (without piped commands)
+func (c *Command) Args() []string {
+	return append([]string(nil), c.args...)
+}
+
+// Replace arguments of the command, eg. for rewriting commands
+// in middleware on a Clone of the command
+func (c *Command) WithArgs(args ...string) *Command {
+	c.args = append([]string(nil), args...)
+	return c
+}
@@ -0,0 +1,108 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	var calls []string
+	r := NewRunner()
+	r.Use(func(next Handler) Handler {
+		return func(ctx context.Context, c *Command) (*Process, error) {
+			calls = append(calls, "outer before")
+			p, err := next(ctx, c)
+			calls = append(calls, "outer after "+p.String())
+			return p, err
+		}
+	}, func(next Handler) Handler {
+		return func(ctx context.Context, c *Command) (*Process, error) {
+			calls = append(calls, "inner before")
+			return next(ctx, c.Clone().WithArgs(append(c.Args(), "barfoo")...))
+		}
+	})
+
+	cmd := r.Cmd("echo", "foobar")
+	p := cmd.Run()
+	if p.String() != "foobar barfoo" {
+		t.Fatal("output not expected:", p.String())
+	}
+	if cmd.ToString() != "echo foobar" {
+		t.Fatal("command modified:", cmd.ToString())
+	}
+	if strings.Join(calls, ",") != "outer before,inner before,outer after foobar barfoo" {
+		t.Fatal("calls not expected:", calls)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	r := NewRunner()
+	r.Use(func(next Handler) Handler {
+		return func(ctx context.Context, c *Command) (*Process, error) {
+			if c.Args()[0] == "rm" {
+				p := &Process{Command: c, ExitStatus: 1}
+				return p, p.Error()
+			}
+			return next(ctx, c)
+		}
+	})
+
+	p, err := r.Cmd("rm", "-rf", "/").RunE()
+	if err == nil || p.ExitStatus != 1 || p.PipeStatus != nil {
+		t.Fatal("command not blocked:", p, err)
+	}
+
+	defer func() {
+		if recovered, ok := recover().(*Process); !ok || recovered.ExitStatus != 1 {
+			t.Fatal("panic not expected:", recovered)
+		}
+	}()
+	r.Cmd("rm", "-rf", "/").Run()
+}
+
+func TestMiddlewareStart(t *testing.T) {
+	var calls []string
+	r := NewRunner()
+	r.Use(func(next Handler) Handler {
+		return func(ctx context.Context, c *Command) (*Process, error) {
+			if c.Args()[0] == "rm" {
+				return nil, errors.New("blocked")
+			}
+			p, err := next(ctx, c.Clone().WithArgs(append(c.Args(), "barfoo")...))
+			calls = append(calls, "after "+p.String())
+			return p, err
+		}
+	})
+
+	if _, err := r.Cmd("rm", "-rf", "/tmp/foobar").Start(); err == nil || err.Error() != "blocked" {
+		t.Fatal("error not expected:", err)
+	}
+
+	rp, err := r.Cmd("echo", "foobar").Start()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	p := rp.Wait()
+	if p.String() != "foobar barfoo" {
+		t.Fatal("output not expected:", p.String())
+	}
+	if strings.Join(calls, ",") != "after foobar barfoo" {
+		t.Fatal("calls not expected:", calls)
+	}
+}
+
+func TestMiddlewareShortCircuitSuccess(t *testing.T) {
+	r := NewRunner()
+	r.Use(func(next Handler) Handler {
+		return func(ctx context.Context, c *Command) (*Process, error) {
+			return &Process{Command: c}, nil
+		}
+	})
+
+	p := r.Cmd("echo", "foobar").Run()
+	if p.String() != "" || len(p.Lines()) != 0 || p.Stderr.Len() != 0 {
+		t.Fatal("process not expected:", p.String())
+	}
+}
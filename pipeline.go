@@ -0,0 +1,344 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Commands connected with OS pipes, all stages are running
+// concurrently and stream data to each other
+type pipeline struct {
+	command  *Command
+	runner   *Runner
+	process  *Process
+	stages   []*stage
+	ctx      context.Context
+	cancel   context.CancelFunc
+	started  time.Time
+	stdout   *captureBuffer
+	stderr   *captureBuffer
+	combined *combinedCapture
+	dryRun   bool
+}
+
+// Single command of a pipeline
+type stage struct {
+	command *Command
+	spawn   *Spawn
+	handle  Handle
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped func() bool
+	lines   []*lineWriter
+
+	// closed when output of the stage is copied to the next stage
+	copied chan struct{}
+
+	// closed after the stage was waited for
+	closeAfterWait []io.Closer
+}
+
+// Commands of the pipe, first command first
+func (c *Command) pipeline() []*Command {
+	var ret []*Command
+	for cmd := c; cmd != nil; cmd = cmd.in {
+		ret = append([]*Command{cmd}, ret...)
+	}
+	return ret
+}
+
+// Start all commands of the pipe
+func (c *Command) start(ctx context.Context, r *Runner, interactive bool) (*pipeline, error) {
+	pl := &pipeline{
+		command: c,
+		runner:  r,
+		process: &Process{Command: c},
+	}
+	p := pl.process
+
+	if c.timeout > 0 {
+		ctx, pl.cancel = context.WithTimeoutCause(ctx, c.timeout, ErrTimeout)
+	}
+	pl.ctx = ctx
+
+	if r.Trace {
+		fmt.Fprintln(os.Stderr, r.TracePrefix, c.ToString())
+	}
+
+	var stdoutErr, stderrErr error
+	pl.stdout, stdoutErr = newCaptureBuffer(r.Capture)
+	pl.stderr, stderrErr = newCaptureBuffer(r.Capture)
+	p.Stdout = pl.stdout.buf
+	p.Stderr = pl.stderr.buf
+	if err := errors.Join(stdoutErr, stderrErr); err != nil {
+		pl.abort()
+		return pl, err
+	}
+	if r.CaptureCombined {
+		pl.combined = &combinedCapture{start: time.Now()}
+	}
+	sharedStderr := &lockedWriter{w: outputWriter(pl.capture(StreamStderr), r.Tee, nil)}
+
+	if r.DryRun != nil {
+		pl.dryRun = true
+		p.PipeStatus = make([]int, len(c.pipeline()))
+		pl.stdout.Write([]byte(r.DryRun.record(c)))
+		pl.stdout.Close()
+		pl.stderr.Close()
+		return pl, nil
+	}
+
+	for _, command := range c.pipeline() {
+		args, err := r.argv(command)
+		if err != nil {
+			pl.abort()
+			return pl, err
+		}
+
+		s := &stage{command: command, ctx: ctx}
+		if command != c && command.timeout > 0 {
+			s.ctx, s.cancel = context.WithTimeoutCause(ctx, command.timeout, ErrTimeout)
+		}
+		s.spawn = &Spawn{
+			Command: command,
+			Args:    args,
+			Env:     command.environ(),
+			Dir:     command.dir,
+			line:    command.shellCmd(false),
+		}
+
+		if interactive {
+			s.spawn.Stderr = os.Stderr
+		} else if lines := newLineWriter(command.stderrLine); lines != nil {
+			s.lines = append(s.lines, lines)
+			s.spawn.Stderr = io.MultiWriter(sharedStderr, lines)
+		} else {
+			s.spawn.Stderr = sharedStderr
+		}
+		pl.stages = append(pl.stages, s)
+	}
+
+	if c.terminal != nil {
+		lines := newLineWriter(c.stdoutLine)
+		if err := pl.attachTerminal(c.terminal, outputWriter(pl.capture(StreamStdout), r.Tee, lines)); err != nil {
+			pl.abort()
+			return pl, err
+		}
+		if lines != nil {
+			pl.stages[0].lines = append(pl.stages[0].lines, lines)
+		}
+		return pl, pl.launch(ctx)
+	}
+
+	// stdin of first command
+	head := pl.stages[0]
+	stdin := c.stdin
+	if stdin == nil {
+		stdin = head.command.stdin
+	}
+	if stdin != nil {
+		in, closer, err := stdin()
+		if err != nil {
+			pl.abort()
+			return pl, err
+		}
+		if closer != nil {
+			head.closeAfterWait = append(head.closeAfterWait, closer)
+		}
+		head.spawn.Stdin = in
+	} else if interactive {
+		head.spawn.Stdin = os.Stdin
+	} else {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			pl.abort()
+			return pl, err
+		}
+		head.spawn.Stdin = pr
+		head.spawn.addPipe(pr)
+		head.closeAfterWait = append(head.closeAfterWait, pw)
+		p.Stdin = pw
+	}
+
+	// connect stages with pipes
+	for i := 0; i+1 < len(pl.stages); i++ {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			pl.abort()
+			return pl, err
+		}
+		downstream := pl.stages[i+1]
+		downstream.spawn.Stdin = pr
+		downstream.spawn.addPipe(pr)
+
+		upstream := pl.stages[i]
+		lines := newLineWriter(upstream.command.stdoutLine)
+		if lines == nil {
+			upstream.spawn.Stdout = pw
+			upstream.spawn.addPipe(pw)
+			continue
+		}
+
+		// copy output to the next stage and the line callback
+		tr, tw, err := os.Pipe()
+		if err != nil {
+			pw.Close()
+			pl.abort()
+			return pl, err
+		}
+		upstream.lines = append(upstream.lines, lines)
+		upstream.spawn.Stdout = tw
+		upstream.spawn.addPipe(tw)
+		upstream.copied = make(chan struct{})
+		go func(copied chan struct{}) {
+			io.Copy(io.MultiWriter(pw, lines), tr)
+			tr.Close()
+			pw.Close()
+			close(copied)
+		}(upstream.copied)
+	}
+
+	// stdout of last command
+	last := pl.stages[len(pl.stages)-1]
+	if interactive {
+		last.spawn.Stdout = os.Stdout
+	} else {
+		lines := newLineWriter(c.stdoutLine)
+		if lines != nil {
+			last.lines = append(last.lines, lines)
+		}
+		last.spawn.Stdout = outputWriter(pl.capture(StreamStdout), r.Tee, lines)
+	}
+
+	return pl, pl.launch(ctx)
+}
+
+// Start the processes of all stages
+func (pl *pipeline) launch(ctx context.Context) error {
+	r, p := pl.runner, pl.process
+	if ctx.Err() != nil {
+		// context already done, don't start the commands at all
+		pl.abort()
+		p.ExitStatus = -1
+		p.interrupted(ctx)
+		return p.Error()
+	}
+
+	pl.started = time.Now()
+	if pl.combined != nil {
+		pl.combined.start = pl.started
+	}
+	executor := r.executor()
+	for _, s := range pl.stages {
+		handle, err := executor.Start(s.spawn)
+		if err != nil {
+			pl.abort()
+			return startError(err)
+		}
+		s.handle = handle
+		s.stopped = watchContext(s.ctx, handle, s.command.grace(r))
+	}
+
+	return nil
+}
+
+// Wait for all commands of the pipe, exit status of the pipe is the
+// last non-zero exit status of the commands (like pipefail)
+func (pl *pipeline) wait() (*Process, error) {
+	if pl.cancel != nil {
+		defer pl.cancel()
+	}
+
+	p := pl.process
+	if pl.dryRun {
+		return p, nil
+	}
+	p.PipeStatus = make([]int, len(pl.stages))
+	p.Stages = make([]ExitState, len(pl.stages))
+
+	var waitErr error
+	for i, s := range pl.stages {
+		state, err := s.handle.Wait()
+		if s.copied != nil {
+			<-s.copied
+		}
+		for _, closer := range s.closeAfterWait {
+			closer.Close()
+		}
+		for _, lines := range s.lines {
+			lines.Flush()
+		}
+		if s.stopped() && (err != nil || state.ExitStatus != 0) {
+			p.interrupted(s.ctx)
+		}
+		if s.cancel != nil {
+			s.cancel()
+		}
+
+		if err != nil {
+			waitErr = err
+			continue
+		}
+		p.PipeStatus[i] = state.ExitStatus
+		p.Stages[i] = state
+		p.Usage.add(state.Usage)
+		if state.ExitStatus != 0 {
+			p.ExitStatus = state.ExitStatus
+			p.Signal = state.Signal
+			p.CoreDump = state.CoreDump
+		}
+	}
+	p.Usage.Duration = time.Since(pl.started)
+
+	pl.stdout.Close()
+	pl.stderr.Close()
+	p.Truncated = pl.stdout.truncated() > 0 || pl.stderr.truncated() > 0
+	p.StdoutFile = pl.stdout.spillFile()
+	p.StderrFile = pl.stderr.spillFile()
+	if pl.combined != nil {
+		p.combined = pl.combined.Chunks()
+	}
+
+	if waitErr != nil {
+		pl.log(waitErr)
+		return p, waitErr
+	}
+	if p.ExitStatus != 0 {
+		err := p.Error()
+		pl.log(err)
+		return p, err
+	}
+	pl.log(nil)
+	return p, nil
+}
+
+// Stop already started commands and release pipes
+func (pl *pipeline) abort() {
+	for _, s := range pl.stages {
+		s.spawn.ClosePipes()
+		if s.handle != nil {
+			s.handle.Signal(syscall.SIGKILL)
+			s.handle.Wait()
+			s.stopped()
+		}
+		if s.copied != nil {
+			<-s.copied
+		}
+		for _, closer := range s.closeAfterWait {
+			closer.Close()
+		}
+		if s.cancel != nil {
+			s.cancel()
+		}
+	}
+	if pl.cancel != nil {
+		pl.cancel()
+	}
+	pl.stdout.Close()
+	pl.stderr.Close()
+}
@@ -0,0 +1,61 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPipeStatus(t *testing.T) {
+	p, err := Cmd("exit 2").Pipe("exit 3").Pipe("cat").RunE()
+	if err == nil {
+		t.Fatal("error expected")
+	}
+	if !reflect.DeepEqual(p.PipeStatus, []int{2, 3, 0}) {
+		t.Fatal("pipe status not expected:", p.PipeStatus)
+	}
+	if p.ExitStatus != 3 {
+		t.Fatal("status not expected:", p.ExitStatus)
+	}
+
+	p = Cmd("echo foobar").Pipe("cat").Run()
+	if !reflect.DeepEqual(p.PipeStatus, []int{0, 0}) {
+		t.Fatal("pipe status not expected:", p.PipeStatus)
+	}
+}
+
+func TestPipeConcurrent(t *testing.T) {
+	start := time.Now()
+	var elapsed time.Duration
+	p := Cmd("echo foobar; sleep 1").Pipe("cat").OnStdoutLine(func(line string) {
+		elapsed = time.Since(start)
+	}).Run()
+
+	if p.String() != "foobar" {
+		t.Fatal("output not expected:", p.String())
+	}
+	if elapsed >= time.Second {
+		t.Fatal("output not streamed between commands:", elapsed)
+	}
+}
+
+func TestPipeLargeOutput(t *testing.T) {
+	p := Cmd("head -c 10000000 /dev/zero").Pipe("wc -c").Pipe("tr -d ' '").Run()
+	if p.String() != "10000000" {
+		t.Fatal("output not expected:", p.String())
+	}
+}
+
+func TestPipeStderr(t *testing.T) {
+	var lines []string
+	p := Cmd("echo foo >&2").OnStderrLine(func(line string) {
+		lines = append(lines, line)
+	}).Pipe("echo bar >&2").Run()
+
+	if !reflect.DeepEqual(lines, []string{"foo"}) {
+		t.Fatal("stderr lines not expected:", lines)
+	}
+	if len(p.Stderr.String()) != 8 {
+		t.Fatal("stderr not expected:", p.Stderr.String())
+	}
+}
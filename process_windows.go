@@ -0,0 +1,25 @@
+//go:build windows
+
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Process groups are not supported, only the shell process is signaled
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// Controlling terminals are not supported
+func setControllingTerminal(cmd *exec.Cmd) {}
+
+// Send signal to the command, windows only supports killing the process
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	return cmd.Process.Kill()
+}
+
+// Maximum resident set size is not reported on windows
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,154 @@
+//go:build linux
+
+package shell
+
+import (
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// Window size of a terminal (struct winsize)
+type winsize struct {
+	Rows   uint16
+	Cols   uint16
+	Xpixel uint16
+	Ypixel uint16
+}
+
+// Call ioctl on the file without switching it to blocking mode
+func ioctl(f *os.File, request uintptr, arg unsafe.Pointer) error {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	err = conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(arg))
+	})
+	if err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Open a new pseudo-terminal pair
+func openPty() (master *os.File, slave *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var unlock int32
+	if err = ioctl(master, syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	var n uint32
+	if err = ioctl(master, syscall.TIOCGPTN, unsafe.Pointer(&n)); err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	slave, err = os.OpenFile("/dev/pts/"+strconv.Itoa(int(n)), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+	return master, slave, nil
+}
+
+// Size of the terminal
+func getWinsize(f *os.File) (int, int, error) {
+	var ws winsize
+	if err := ioctl(f, syscall.TIOCGWINSZ, unsafe.Pointer(&ws)); err != nil {
+		return 0, 0, err
+	}
+	return int(ws.Rows), int(ws.Cols), nil
+}
+
+// Set size of the terminal
+func setWinsize(f *os.File, rows, cols int) error {
+	ws := winsize{Rows: uint16(rows), Cols: uint16(cols)}
+	return ioctl(f, syscall.TIOCSWINSZ, unsafe.Pointer(&ws))
+}
+
+// Switch terminal to raw mode (like cfmakeraw), nothing is changed
+// if f is not a terminal
+func makeRaw(f *os.File) (func(), error) {
+	var state syscall.Termios
+	if err := ioctl(f, syscall.TCGETS, unsafe.Pointer(&state)); err != nil {
+		return func() {}, nil
+	}
+
+	raw := state
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := ioctl(f, syscall.TCSETS, unsafe.Pointer(&raw)); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		ioctl(f, syscall.TCSETS, unsafe.Pointer(&state))
+	}, nil
+}
+
+// Non-blocking duplicate of f supporting read deadlines (if f is
+// pollable), restore switches f back to blocking mode
+func pollableDup(f *os.File) (*os.File, func(), error) {
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		return nil, nil, err
+	}
+	flags, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_GETFL, 0)
+	if errno != 0 {
+		syscall.Close(fd)
+		return nil, nil, errno
+	}
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, nil, err
+	}
+
+	restore := func() {
+		if flags&syscall.O_NONBLOCK == 0 {
+			syscall.SetNonblock(int(f.Fd()), false)
+		}
+	}
+	return os.NewFile(uintptr(fd), f.Name()), restore, nil
+}
+
+// Forward SIGWINCH of the current process to ch
+func notifyResize(ch chan<- struct{}) func() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, syscall.SIGWINCH)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-signals:
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
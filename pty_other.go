@@ -0,0 +1,32 @@
+//go:build !linux
+
+package shell
+
+import (
+	"os"
+)
+
+// Pseudo-terminals are only supported on linux
+func openPty() (*os.File, *os.File, error) {
+	return nil, nil, ErrPtyNotSupported
+}
+
+func getWinsize(f *os.File) (int, int, error) {
+	return 0, 0, ErrPtyNotSupported
+}
+
+func setWinsize(f *os.File, rows, cols int) error {
+	return ErrPtyNotSupported
+}
+
+func makeRaw(f *os.File) (func(), error) {
+	return nil, ErrPtyNotSupported
+}
+
+func pollableDup(f *os.File) (*os.File, func(), error) {
+	return nil, nil, ErrPtyNotSupported
+}
+
+func notifyResize(ch chan<- struct{}) func() {
+	return func() {}
+}
@@ -0,0 +1,119 @@
+package shell
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Replacement for redacted secrets
+var RedactedValue = "***"
+
+// Redactor used by NewRunner and the package level functions
+var DefaultRedactor = NewRedactor()
+
+// Registry of secrets which are masked whenever commands or their
+// stderr are rendered (trace, ToString, Debug, Error and logs).
+// Commands are always executed with the real values.
+type Redactor struct {
+	mu       sync.RWMutex
+	secrets  []string
+	patterns []*regexp.Regexp
+}
+
+// Create an empty redactor
+func NewRedactor() *Redactor {
+	return &Redactor{}
+}
+
+// Register literal secrets, the shell quoted form of a secret
+// is redacted as well
+func (r *Redactor) AddSecret(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		r.secrets = append(r.secrets, secret)
+		if quoted := strings.Replace(secret, "'", "'\\''", -1); quoted != secret {
+			r.secrets = append(r.secrets, quoted)
+		}
+	}
+	// longest secrets first so overlapping secrets are masked completely
+	sort.SliceStable(r.secrets, func(i, j int) bool {
+		return len(r.secrets[i]) > len(r.secrets[j])
+	})
+}
+
+// Register patterns of secrets, only the submatches are redacted if
+// the pattern contains groups (eg. `--password=(\S+)`), otherwise
+// the whole match
+func (r *Redactor) AddPattern(patterns ...*regexp.Regexp) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.patterns = append(r.patterns, patterns...)
+}
+
+// Mask all registered secrets in s
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, secret := range r.secrets {
+		s = strings.Replace(s, secret, RedactedValue, -1)
+	}
+	for _, pattern := range r.patterns {
+		s = redactPattern(pattern, s)
+	}
+	return s
+}
+
+// Mask matches (or their submatches) of pattern in s
+func redactPattern(pattern *regexp.Regexp, s string) string {
+	if pattern.NumSubexp() == 0 {
+		return pattern.ReplaceAllLiteralString(s, RedactedValue)
+	}
+
+	var ret strings.Builder
+	last := 0
+	for _, match := range pattern.FindAllStringSubmatchIndex(s, -1) {
+		for i := 2; i < len(match); i += 2 {
+			if match[i] < last {
+				continue
+			}
+			ret.WriteString(s[last:match[i]])
+			ret.WriteString(RedactedValue)
+			last = match[i+1]
+		}
+	}
+	ret.WriteString(s[last:])
+	return ret.String()
+}
+
+// Register literal secrets in the DefaultRedactor
+func AddSecret(secrets ...string) {
+	DefaultRedactor.AddSecret(secrets...)
+}
+
+// Register patterns of secrets in the DefaultRedactor
+func AddSecretPattern(patterns ...*regexp.Regexp) {
+	DefaultRedactor.AddPattern(patterns...)
+}
+
+// Mask secrets of the DefaultRedactor in s
+func Redact(s string) string {
+	return DefaultRedactor.Redact(s)
+}
+
+// Mask secrets of the redactor of the command runner in s
+func (c *Command) redact(s string) string {
+	if c == nil {
+		return DefaultRedactor.Redact(s)
+	}
+	return c.getRunner().Redactor.Redact(s)
+}
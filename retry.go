@@ -0,0 +1,111 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Policy for retrying failed commands
+type RetryPolicy struct {
+	// Maximum number of attempts including the first one
+	MaxAttempts int
+
+	// Exit codes which are retried, all failures are retried
+	// if neither ExitCodes nor Retryable is set
+	ExitCodes []int
+
+	// Decides if a failed process is retried (optional)
+	Retryable func(p *Process) bool
+
+	// Delay before the first retry, doubled for every further retry
+	Backoff time.Duration
+
+	// Maximum delay between attempts (optional)
+	MaxBackoff time.Duration
+
+	// Random deviation of the delay as fraction (eg. 0.2 for +/-20%)
+	Jitter float64
+}
+
+// Retry failed executions of the command (including all piped commands)
+// according to policy. Retries stop as soon as the context of the command
+// is done. Stdin set with WithStdin is consumed by the first attempt,
+// use StdinString, StdinBytes or StdinFile for retried commands. Commands
+// started with Start are retried in background, output readers of the
+// RunningProcess only return the output of the first attempt.
+func (c *Command) Retry(policy RetryPolicy) *Command {
+	c.retry = &policy
+	return c
+}
+
+// Execute command with handler until it succeeds or is not retryable
+func (policy *RetryPolicy) run(ctx context.Context, c *Command, handler Handler) (*Process, error) {
+	var attempts []*Process
+	for attempt := 1; ; attempt++ {
+		p, err := handler(ctx, c)
+		if p != nil {
+			p.Attempts = attempts
+		}
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(p, err) {
+			return p, err
+		}
+		attempts = append(attempts, p)
+
+		timer := time.NewTimer(policy.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return p, err
+		case <-timer.C:
+		}
+	}
+}
+
+// Check if failed process should be retried
+func (policy *RetryPolicy) retryable(p *Process, err error) bool {
+	var exitErr *ExitError
+	if p == nil || p.Canceled || !errors.As(err, &exitErr) {
+		return false
+	}
+	if policy.Retryable != nil && policy.Retryable(p) {
+		return true
+	}
+	for _, code := range policy.ExitCodes {
+		if code == p.ExitStatus {
+			return true
+		}
+	}
+	return policy.Retryable == nil && len(policy.ExitCodes) == 0
+}
+
+// Delay before the next attempt (exponential backoff with jitter)
+func (policy *RetryPolicy) delay(attempt int) time.Duration {
+	maxDelay := time.Duration(math.MaxInt64)
+	if policy.MaxBackoff > 0 {
+		maxDelay = policy.MaxBackoff
+	}
+
+	// stop doubling before the delay overflows
+	delay := policy.Backoff
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		if delay > maxDelay/2 {
+			delay = maxDelay
+		} else {
+			delay *= 2
+		}
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if policy.Jitter > 0 {
+		jittered := float64(delay) * (1 + (rand.Float64()*2-1)*policy.Jitter)
+		if jittered >= float64(math.MaxInt64) {
+			return time.Duration(math.MaxInt64)
+		}
+		delay = time.Duration(jittered)
+	}
+	return delay
+}
@@ -0,0 +1,102 @@
+package shell
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "counter")
+	r := NewRunner()
+	r.Panic = false
+
+	// fails twice with 255, succeeds on third attempt
+	p := r.Cmd("echo x >> " + counter + "; test $(wc -l < " + counter + ") -ge 3 || exit 255; cat").
+		StdinString("foobar").
+		Retry(RetryPolicy{MaxAttempts: 5, ExitCodes: []int{255}, Backoff: time.Millisecond}).
+		Run()
+	if p.ExitStatus != 0 || p.String() != "foobar" {
+		t.Fatal("process not expected:", p.Debug())
+	}
+	if len(p.Attempts) != 2 || p.Attempts[0].ExitStatus != 255 || p.Attempts[1].ExitStatus != 255 {
+		t.Fatal("attempts not expected:", p.Attempts)
+	}
+
+	os.Remove(counter)
+	failed := 0
+	r.ErrorFunc = func(c *Command, p *Process) { failed++ }
+	p = r.Cmd("echo x >> " + counter + "; exit 3").
+		Retry(RetryPolicy{MaxAttempts: 5, ExitCodes: []int{255}}).
+		Run()
+	if p.ExitStatus != 3 || len(p.Attempts) != 0 || failed != 1 {
+		t.Fatal("not retryable exit code retried:", p.Attempts, failed)
+	}
+
+	p = r.Cmd("exit 4").
+		Retry(RetryPolicy{MaxAttempts: 3, Retryable: func(p *Process) bool { return p.ExitStatus == 4 }}).
+		Run()
+	if len(p.Attempts) != 2 || failed != 2 {
+		t.Fatal("attempts not expected:", p.Attempts, failed)
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	r := NewRunner()
+	start := time.Now()
+	p, err := r.CmdContext(ctx, "exit 1").
+		Retry(RetryPolicy{MaxAttempts: 10, Backoff: time.Minute}).
+		RunE()
+	if err == nil || len(p.Attempts) != 0 || time.Since(start) > 5*time.Second {
+		t.Fatal("retry not stopped by context:", p.Attempts, time.Since(start))
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	for attempt, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if delay := policy.delay(attempt + 1); delay != expected {
+			t.Fatal("delay not expected:", attempt+1, delay)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if delay := policy.delay(1); delay < 500*time.Millisecond || delay > 1500*time.Millisecond {
+			t.Fatal("delay not expected:", delay)
+		}
+	}
+
+	// without MaxBackoff the delay must not overflow
+	policy = RetryPolicy{Backoff: time.Second, Jitter: 0.5}
+	last := time.Duration(0)
+	for _, attempt := range []int{1, 10, 40, 64, 100} {
+		policy.Jitter = 0
+		delay := policy.delay(attempt)
+		if delay <= 0 || delay < last {
+			t.Fatal("delay not expected:", attempt, delay)
+		}
+		last = delay
+		policy.Jitter = 0.5
+		if delay := policy.delay(attempt); delay <= 0 {
+			t.Fatal("delay not expected:", attempt, delay)
+		}
+	}
+}
+
+func TestRetryStart(t *testing.T) {
+	r := NewRunner()
+	rp, err := r.Cmd("echo attempt; exit 1").Retry(RetryPolicy{MaxAttempts: 3}).Start()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	p, err := rp.WaitE()
+	if err == nil || len(p.Attempts) != 2 {
+		t.Fatal("command not retried:", len(p.Attempts), err)
+	}
+}
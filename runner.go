@@ -0,0 +1,309 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Runner holds the configuration for command execution, commands created
+// by a runner are executed with its settings. Different runners can be
+// used concurrently, a runner must not be modified while in use.
+type Runner struct {
+	// Shell for command invocation, defaults to the dialect selected
+	// by SetDefaultShell or the sh dialect (probed for pipefail)
+	Shell []string
+
+	// Specifies if panic is thrown if command fails
+	Panic bool
+
+	// Error func hook if command fails
+	ErrorFunc func(c *Command, p *Process)
+
+	// Timeout func hook if command is killed because of its timeout
+	TimeoutFunc func(c *Command, p *Process)
+
+	// Verbose func hook before command is executed
+	VerboseFunc func(c *Command)
+
+	// Trace command for each executed
+	Trace bool
+
+	// Trace output prefix
+	TracePrefix string
+
+	// Writer which receives a copy of stdout and stderr
+	Tee io.Writer
+
+	// Time between SIGTERM and SIGKILL when a command is stopped
+	GracePeriod time.Duration
+
+	// Execute commands directly without shell (see Command.Exec)
+	Exec bool
+
+	// Record commands in plan instead of executing them (dry-run)
+	DryRun *Plan
+
+	// Executor starting the processes (defaults to DefaultExecutor)
+	Executor Executor
+
+	// Limits for captured stdout and stderr (unlimited by default)
+	Capture CaptureLimit
+
+	// Secrets masked when commands or stderr are rendered
+	Redactor *Redactor
+
+	// Record interleaved stdout and stderr (see Process.Combined),
+	// not limited by Capture
+	CaptureCombined bool
+
+	// Structured logger receiving one record per execution (optional),
+	// also warned once if the default shell does not support pipefail
+	Logger *slog.Logger
+
+	// Fail commands with ErrPipefailNotSupported instead of running
+	// them if the default shell does not support pipefail
+	RequirePipefail bool
+
+	// Middleware wrapping command execution, first registered is outermost
+	middleware []Middleware
+}
+
+// Runner used by the package level functions. The package variables
+// Shell, Panic, Trace, TracePrefix, Tee and GracePeriod are applied to
+// it when they are changed, so either of them can be used for
+// configuration. The hooks of DefaultRunner call the package level
+// ErrorFunc, TimeoutFunc and VerboseFunc unless they are replaced.
+var DefaultRunner = newDefaultRunner()
+
+// Package variables which can be set instead of DefaultRunner fields
+type packageVars struct {
+	shell       []string
+	panic       bool
+	trace       bool
+	tracePrefix string
+	tee         io.Writer
+	gracePeriod time.Duration
+}
+
+var (
+	defaultRunnerMu sync.Mutex
+
+	// package variables as last applied to DefaultRunner
+	appliedVars = currentVars()
+)
+
+// Current values of the package variables
+func currentVars() packageVars {
+	return packageVars{
+		shell:       Shell,
+		panic:       Panic,
+		trace:       Trace,
+		tracePrefix: TracePrefix,
+		tee:         Tee,
+		gracePeriod: GracePeriod,
+	}
+}
+
+// Create runner with hooks calling the package level hooks, funcs
+// can't be compared so the package hooks are looked up on every call
+func newDefaultRunner() *Runner {
+	r := NewRunner()
+	r.ErrorFunc = func(c *Command, p *Process) { ErrorFunc(c, p) }
+	r.TimeoutFunc = func(c *Command, p *Process) { TimeoutFunc(c, p) }
+	r.VerboseFunc = func(c *Command) { VerboseFunc(c) }
+	return r
+}
+
+// Create a new runner with default settings
+func NewRunner() *Runner {
+	return &Runner{
+		Panic:       true,
+		ErrorFunc:   func(c *Command, p *Process) {},
+		TimeoutFunc: func(c *Command, p *Process) {},
+		VerboseFunc: func(c *Command) {},
+		TracePrefix: "+",
+		GracePeriod: 5 * time.Second,
+		Redactor:    DefaultRedactor,
+	}
+}
+
+// Snapshot of DefaultRunner, package variables changed since the
+// last call are applied to DefaultRunner first
+func defaultRunner() *Runner {
+	defaultRunnerMu.Lock()
+	defer defaultRunnerMu.Unlock()
+
+	vars, applied := currentVars(), appliedVars
+	if !slices.Equal(vars.shell, applied.shell) {
+		DefaultRunner.Shell = vars.shell
+		// shell set by SetDefaultShell, the dialect is resolved
+		// (and probed for pipefail) by the runner
+		if d := getDefaultDialect(); d != nil && slices.Equal(vars.shell, d.argv(d.Pipefail)) {
+			DefaultRunner.Shell = nil
+		}
+	}
+	if vars.panic != applied.panic {
+		DefaultRunner.Panic = vars.panic
+	}
+	if vars.trace != applied.trace {
+		DefaultRunner.Trace = vars.trace
+	}
+	if vars.tracePrefix != applied.tracePrefix {
+		DefaultRunner.TracePrefix = vars.tracePrefix
+	}
+	if vars.tee != applied.tee {
+		DefaultRunner.Tee = vars.tee
+	}
+	if vars.gracePeriod != applied.gracePeriod {
+		DefaultRunner.GracePeriod = vars.gracePeriod
+	}
+	appliedVars = vars
+
+	r := *DefaultRunner
+	return &r
+}
+
+// Create new Command instance executed by this runner
+func (r *Runner) Cmd(cmd ...interface{}) *Command {
+	c := new(Command)
+	c.runner = r
+	c.addArgs(cmd...)
+	return c
+}
+
+// Create new Command instance executed by this runner bound to ctx
+func (r *Runner) CmdContext(ctx context.Context, cmd ...interface{}) *Command {
+	c := r.Cmd(cmd...)
+	c.ctx = ctx
+	return c
+}
+
+// Create a new shell command instance using strings as parameters
+func (r *Runner) NewCmd(command string, args ...string) *Command {
+	c := NewCmd(command, args...)
+	c.runner = r
+	return c
+}
+
+// Run command using this runner
+func (r *Runner) Run(cmd ...interface{}) *Process {
+	return r.Cmd(cmd...).Run()
+}
+
+// Program and arguments for a command, either using the shell
+// or directly in exec mode
+func (r *Runner) argv(c *Command) ([]string, error) {
+	// compound commands and redirections need the shell
+	if c.exec || (r.Exec && !c.shell) {
+		return c.argv()
+	}
+
+	shell, err := r.shell()
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]string(nil), shell...), c.shellCmd(false)), nil
+}
+
+// Executor of the runner, defaults to DefaultExecutor
+func (r *Runner) executor() Executor {
+	if r.Executor == nil {
+		return DefaultExecutor
+	}
+	return r.Executor
+}
+
+// Shell of the runner, defaults to the dialect selected by
+// SetDefaultShell or the sh dialect (probed for pipefail)
+func (r *Runner) shell() ([]string, error) {
+	if len(r.Shell) > 0 {
+		return r.Shell, nil
+	}
+	d := getDefaultDialect()
+	if d == nil {
+		d, _ = GetDialect("sh")
+	}
+	if !d.SupportsPipefail() {
+		if r.RequirePipefail {
+			return nil, fmt.Errorf("%w: %s", ErrPipefailNotSupported, strings.Join(d.Command, " "))
+		}
+		if r.Logger != nil {
+			d.warn.Do(func() {
+				r.Logger.Warn("shell does not support pipefail, failures of inline pipes are not detected",
+					slog.String("shell", strings.Join(d.Command, " ")))
+			})
+		}
+	}
+	return d.Argv(), nil
+}
+
+// Call error or timeout hook if the process failed
+func (r *Runner) fail(c *Command, p *Process, err error) {
+	var exitErr *ExitError
+	if p == nil || !errors.As(err, &exitErr) {
+		return
+	}
+	if p.TimedOut {
+		if r.TimeoutFunc != nil {
+			r.TimeoutFunc(c, p)
+		}
+	} else if r.ErrorFunc != nil {
+		r.ErrorFunc(c, p)
+	}
+}
+
+// Panic with the failed process if Panic is enabled. Errors which
+// prevented the command from running (eg. a missing StdinFile) are
+// recorded on a failed process (see Process.Err).
+func (r *Runner) check(c *Command, p *Process, err error) *Process {
+	if err == nil {
+		return p
+	}
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) {
+		p = failedProcess(c, p, err)
+	}
+	if r.Panic {
+		panic(p)
+	}
+	return p
+}
+
+// Failed process for an error which prevented the command from running
+func failedProcess(c *Command, p *Process, err error) *Process {
+	if p == nil {
+		p = &Process{Command: c}
+	}
+	if p.Stdout == nil {
+		p.Stdout = new(bytes.Buffer)
+	}
+	if p.Stderr == nil {
+		p.Stderr = new(bytes.Buffer)
+	}
+	switch {
+	case errors.Is(err, ErrCommandNotFound):
+		p.ExitStatus = 127
+	case errors.Is(err, ErrNotExecutable):
+		p.ExitStatus = 126
+	default:
+		p.ExitStatus = -1
+	}
+	p.Err = err
+	fmt.Fprintln(p.Stderr, err)
+	return p
+}
+
+// Call verbose hook before command is executed
+func (r *Runner) verbose(c *Command) {
+	if r.VerboseFunc != nil {
+		r.VerboseFunc(c)
+	}
+}
@@ -0,0 +1,116 @@
+package shell
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRunnerRun(t *testing.T) {
+	r := NewRunner()
+	r.Shell = ShellList["bash"]
+
+	output := r.Run("echo", "foobar").String()
+	if output != "foobar" {
+		t.Fatal("output not expected:", output)
+	}
+}
+
+func TestRunnerPipe(t *testing.T) {
+	r := NewRunner()
+	r.Panic = false
+
+	p := r.Cmd("echo foobar").Pipe("exit 3").Run()
+	if p.ExitStatus != 3 {
+		t.Fatal("status not expected:", p.ExitStatus)
+	}
+}
+
+func TestRunnerConcurrent(t *testing.T) {
+	bash := NewRunner()
+	bash.Shell = ShellList["bash"]
+
+	sh := NewRunner()
+	sh.Panic = false
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if p := recover().(*Process); p.ExitStatus != 2 {
+					t.Error("status not expected:", p.ExitStatus)
+				}
+			}()
+			bash.Run("exit", "2")
+			t.Error("bash runner did not panic")
+		}()
+		go func() {
+			defer wg.Done()
+			if p := sh.Run("exit", "2"); p.ExitStatus != 2 {
+				t.Error("status not expected:", p.ExitStatus)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDefaultRunner(t *testing.T) {
+	defer func(panic bool, trace bool) {
+		DefaultRunner.Panic = panic
+		Panic = panic
+		DefaultRunner.Trace = trace
+	}(DefaultRunner.Panic, DefaultRunner.Trace)
+
+	DefaultRunner.Panic = false
+	if p := Run("exit 2"); p.ExitStatus != 2 {
+		t.Fatal("status not expected:", p.ExitStatus)
+	}
+
+	// package variable set back to its last applied value is not a change
+	Panic = false
+	Panic = true
+	func() {
+		defer func() {
+			if recover() != nil {
+				t.Fatal("unchanged package variable applied")
+			}
+		}()
+		Run("exit 2")
+	}()
+
+	// package variable changed afterwards takes precedence
+	Panic = false
+	Run("true")
+	Panic = true
+	defer func() {
+		if p, ok := recover().(*Process); !ok || p.ExitStatus != 2 {
+			t.Fatal("package variable not applied:", p)
+		}
+	}()
+	Run("exit 2")
+}
+
+func TestDefaultRunnerHooks(t *testing.T) {
+	defer func(errorFunc func(c *Command, p *Process), panic bool) {
+		ErrorFunc = errorFunc
+		Panic = panic
+	}(ErrorFunc, Panic)
+	Panic = false
+
+	var called []string
+	hook := func(name string) func(c *Command, p *Process) {
+		return func(c *Command, p *Process) {
+			called = append(called, name)
+		}
+	}
+
+	ErrorFunc = hook("a")
+	Run("exit 1")
+	ErrorFunc = hook("b")
+	Run("exit 1")
+	if strings.Join(called, ",") != "a,b" {
+		t.Fatal("hooks not expected:", called)
+	}
+}
@@ -0,0 +1,131 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// Handle of a command running in background
+type RunningProcess struct {
+	pipeline *pipeline
+
+	done    chan struct{}
+	process *Process
+	err     error
+}
+
+// Start command in background, use Wait to wait for the command.
+// The middleware chain of the runner runs in background until the
+// command is finished, Start fails if it returns without starting
+// the command. If the command is started more than once (retried by
+// its RetryPolicy or by middleware) Pid, Stdin and the output readers
+// refer to the first started command.
+func (c *Command) Start() (*RunningProcess, error) {
+	r := c.getRunner()
+	r.verbose(c)
+	rp := &RunningProcess{
+		done: make(chan struct{}),
+	}
+
+	started := make(chan *pipeline, 1)
+	var once sync.Once
+	handler := r.handler(func(ctx context.Context, c *Command) (*Process, error) {
+		pl, err := c.start(ctx, r, false)
+		if err != nil {
+			return pl.process, err
+		}
+		once.Do(func() { started <- pl })
+		return pl.wait()
+	})
+
+	// wait in background so output readers receive EOF
+	// as soon as the command is finished
+	go func() {
+		if c.retry != nil {
+			rp.process, rp.err = c.retry.run(c.context(), c, handler)
+		} else {
+			rp.process, rp.err = handler(c.context(), c)
+		}
+		if rp.process != nil || rp.err == nil {
+			rp.process = completeProcess(c, rp.process)
+		}
+		r.fail(c, rp.process, rp.err)
+		close(rp.done)
+	}()
+
+	select {
+	case rp.pipeline = <-started:
+		return rp, nil
+	case <-rp.done:
+	}
+
+	// the command may have been started and finished already
+	select {
+	case rp.pipeline = <-started:
+		return rp, nil
+	default:
+	}
+	if rp.err == nil {
+		return nil, errors.New("command was not started by middleware")
+	}
+	return nil, rp.err
+}
+
+// Process id of the (last) command, 0 in dry-run mode
+func (rp *RunningProcess) Pid() int {
+	stages := rp.pipeline.stages
+	if len(stages) == 0 {
+		return 0
+	}
+	return stages[len(stages)-1].handle.Pid()
+}
+
+// Send signal to the process groups of all piped commands
+func (rp *RunningProcess) Signal(sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return errors.New("unsupported signal type")
+	}
+	var ret error
+	for _, stage := range rp.pipeline.stages {
+		if err := stage.handle.Signal(s); err != nil {
+			ret = err
+		}
+	}
+	return ret
+}
+
+// Stdin of the (first) command, close it to signal end of input
+func (rp *RunningProcess) Stdin() io.WriteCloser {
+	return rp.pipeline.process.Stdin
+}
+
+// Reader for stdout of the command from the beginning, reads block
+// until output is available. The reader has to be consumed before the
+// stdout of the returned Process is read.
+func (rp *RunningProcess) Stdout() io.Reader {
+	return rp.pipeline.stdout.NewReader()
+}
+
+// Reader for stderr of all commands from the beginning, reads block
+// until output is available. The reader has to be consumed before the
+// stderr of the returned Process is read.
+func (rp *RunningProcess) Stderr() io.Reader {
+	return rp.pipeline.stderr.NewReader()
+}
+
+// Wait for command, failures panic if Panic is enabled for the runner
+func (rp *RunningProcess) Wait() *Process {
+	p, err := rp.WaitE()
+	return rp.pipeline.runner.check(rp.pipeline.command, p, err)
+}
+
+// Wait for command and return failures as error instead of panicking
+func (rp *RunningProcess) WaitE() (*Process, error) {
+	<-rp.done
+	return rp.process, rp.err
+}
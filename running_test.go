@@ -0,0 +1,56 @@
+package shell
+
+import (
+	"bufio"
+	"io"
+	"syscall"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	rp, err := Cmd("cat").Pipe("cat").Start()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if rp.Pid() <= 0 {
+		t.Fatal("pid not expected:", rp.Pid())
+	}
+
+	stdout := bufio.NewReader(rp.Stdout())
+	io.WriteString(rp.Stdin(), "foo\n")
+	line, err := stdout.ReadString('\n')
+	if err != nil || line != "foo\n" {
+		t.Fatal("output not expected:", line, err)
+	}
+
+	io.WriteString(rp.Stdin(), "bar\n")
+	rp.Stdin().Close()
+
+	rest, _ := io.ReadAll(stdout)
+	if string(rest) != "bar\n" {
+		t.Fatal("output not expected:", string(rest))
+	}
+
+	p := rp.Wait()
+	if p.String() != "foo\nbar" {
+		t.Fatal("output not expected:", p.String())
+	}
+}
+
+func TestStartSignal(t *testing.T) {
+	rp, err := Cmd("sleep 5").Start()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if err := rp.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	p, err := rp.WaitE()
+	if err == nil {
+		t.Fatal("error expected")
+	}
+	if p.Signal != syscall.SIGTERM {
+		t.Fatal("signal not expected:", p.Signal)
+	}
+}
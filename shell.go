@@ -2,25 +2,28 @@ package shell
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 )
 
 var (
-	// Available shell list
+	// Available shell list (see Dialect for more shells)
 	ShellList   = map[string][]string{
 		"zsh":  {"/bin/zsh",  "-o", "errexit", "-o", "pipefail", "-c"},
 		"bash": {"/bin/bash",  "-o", "errexit", "-o", "pipefail", "-c"},
 		"sh":   {"/bin/sh",  "-o", "errexit", "-c"},
 	}
 
-	// Shell for command invocation
-	Shell       = []string{"/bin/sh",  "-o", "errexit", "-c"}
+	// Shell for command invocation and usage in ShellCommandBuilder,
+	// runners without Shell use the default dialect instead (with
+	// pipefail if supported by /bin/sh)
+	Shell       = []string{"/bin/sh", "-o", "errexit", "-c"}
 
 	// Specifies if panic is thrown if command fails
 	Panic       = true
@@ -28,6 +31,12 @@ var (
 	// Error func hook if command fails
 	ErrorFunc   = func(c *Command, p *Process) {}
 
+	// Timeout func hook if command is killed because of its timeout
+	TimeoutFunc = func(c *Command, p *Process) {}
+
+	// Time between SIGTERM and SIGKILL when a command is stopped
+	GracePeriod = 5 * time.Second
+
 	// Verbose func hook before command is executed
 	VerboseFunc = func(c *Command) {}
 
@@ -42,19 +51,19 @@ var (
 
 var Tee io.Writer
 
-func assert(err error) {
-	if err != nil {
-		panic(err)
-	}
-}
-
 // Sets the default shell (eg. "sh" or "bash") for command execution
-// and usage in ShellCommandBuilder
+// and usage in ShellCommandBuilder, see RegisterDialect for adding shells.
+// Shell isn't probed for pipefail as it's used for remote shells as well,
+// Quote uses the quoting of the dialect afterwards.
 func SetDefaultShell(shell string) {
-	if val, ok := ShellList[shell]; ok {
+	if d, ok := GetDialect(shell); ok {
+		Shell = d.argv(d.Pipefail)
+		setDefaultDialect(d)
+	} else if val, ok := ShellList[shell]; ok {
 		Shell = val
+		setDefaultDialect(nil)
 	} else {
-		panic(fmt.Sprintf("Shell %v is not supported"))
+		panic(fmt.Sprintf("Shell %v is not supported", shell))
 	}
 }
 
@@ -86,8 +95,17 @@ func PathTemplate(parts ...string) func(...interface{}) string {
 	}
 }
 
-// Quote shell arguments as string
+// Quote shell arguments as string for the default shell
+// (see SetDefaultShell)
 func Quote(arg string) string {
+	if d := getDefaultDialect(); d != nil {
+		return d.QuoteArg(arg)
+	}
+	return quotePosix(arg)
+}
+
+// Quote argument for POSIX shells
+func quotePosix(arg string) string {
 	return fmt.Sprintf("'%s'", strings.Replace(arg, "'", "'\\''", -1))
 }
 
@@ -100,24 +118,61 @@ func QuoteValues(arg ...string) []string {
 }
 
 func ErrExit() {
-	if p, ok := recover().(*Process); p != nil {
-		if !ok {
-			fmt.Fprintf(os.Stderr, "Unexpected panic: %v\n", p)
-			exit(1)
-		}
+	switch p := recover().(type) {
+	case nil:
+	case *Process:
 		fmt.Fprintf(os.Stderr, "%s\n", p.Error())
 		exit(p.ExitStatus)
+	default:
+		fmt.Fprintf(os.Stderr, "Unexpected panic: %v\n", p)
+		exit(1)
+	}
+}
+
+// Error of a recovered panic of Run
+func recoveredError(r interface{}) error {
+	switch v := r.(type) {
+	case nil:
+		return nil
+	case *Process:
+		return v.Error()
+	default:
+		return fmt.Errorf("panic: %v", v)
 	}
 }
 
 type Command struct {
-	args []string
-	in   *Command
+	args        []string
+	in          *Command
+	ctx         context.Context
+	runner      *Runner
+	timeout     time.Duration
+	gracePeriod time.Duration
+	stdoutLine  func(string)
+	stderrLine  func(string)
+	stdin       func() (io.Reader, io.Closer, error)
+	retry       *RetryPolicy
+	terminal    Terminal
+	level       int
+	env         []string
+	clearEnv    bool
+	dir         string
+	exec        bool
+	shell       bool
+}
+
+// Create a copy of the command which can be extended without
+// modifying the original one
+func (c *Command) Clone() *Command {
+	cmd := *c
+	cmd.args = append([]string(nil), c.args...)
+	cmd.env = append([]string(nil), c.env...)
+	return &cmd
 }
 
 func (c *Command) ProcFn() func(...interface{}) *Process {
 	return func(args ...interface{}) *Process {
-		cmd := &Command{c.args, c.in}
+		cmd := c.Clone()
 		cmd.addArgs(args...)
 		return cmd.Run()
 	}
@@ -125,15 +180,11 @@ func (c *Command) ProcFn() func(...interface{}) *Process {
 
 func (c *Command) OutputFn() func(...interface{}) (string, error) {
 	return func(args ...interface{}) (out string, err error) {
-		cmd := &Command{c.args, c.in}
+		cmd := c.Clone()
 		cmd.addArgs(args...)
 		defer func() {
-			if p, ok := recover().(*Process); p != nil {
-				if ok {
-					err = p.Error()
-				} else {
-					err = fmt.Errorf("panic: %v", p)
-				}
+			if r := recover(); r != nil {
+				err = recoveredError(r)
 			}
 		}()
 		out = cmd.Run().String()
@@ -143,15 +194,11 @@ func (c *Command) OutputFn() func(...interface{}) (string, error) {
 
 func (c *Command) ErrFn() func(...interface{}) error {
 	return func(args ...interface{}) (err error) {
-		cmd := &Command{c.args, c.in}
+		cmd := c.Clone()
 		cmd.addArgs(args...)
 		defer func() {
-			if p, ok := recover().(*Process); p != nil {
-				if ok {
-					err = p.Error()
-				} else {
-					err = fmt.Errorf("panic: %v", p)
-				}
+			if r := recover(); r != nil {
+				err = recoveredError(r)
 			}
 		}()
 		cmd.Run()
@@ -161,7 +208,10 @@ func (c *Command) ErrFn() func(...interface{}) error {
 
 // Add command as pipe to an existing command
 func (c *Command) Pipe(cmd ...interface{}) *Command {
-	return Cmd(append(cmd, c)...)
+	pipe := Cmd(append(cmd, c)...)
+	pipe.ctx = c.ctx
+	pipe.runner = c.runner
+	return pipe
 }
 
 func (c *Command) addArgs(args ...interface{}) {
@@ -195,12 +245,18 @@ func (c *Command) shellCmd(quote bool) string {
 	return strings.Join(quoted, " ")
 }
 
-// Create human readable command as string
+// Create human readable command as string, registered secrets
+// are redacted
 func (c *Command) ToString() string {
+	return c.redact(c.rawString())
+}
+
+// Command including piped commands as string without redaction
+func (c *Command) rawString() string {
 	var ret []string
 
 	if c.in != nil {
-		ret = append(ret, c.in.ToString())
+		ret = append(ret, c.in.operand(levelPipe))
 	}
 
 	ret = append(ret, c.shellCmd(false))
@@ -208,69 +264,111 @@ func (c *Command) ToString() string {
 	return strings.Join(ret, " | ")
 }
 
+// Context of the command, defaults to context.Background()
+func (c *Command) context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+// Set timeout for command execution, the command (including all piped
+// commands) is stopped with SIGTERM and SIGKILL after the timeout
+func (c *Command) WithTimeout(timeout time.Duration) *Command {
+	c.timeout = timeout
+	return c
+}
+
+// Set time between SIGTERM and SIGKILL when command is stopped
+// (defaults to GracePeriod of the runner)
+func (c *Command) WithGracePeriod(gracePeriod time.Duration) *Command {
+	c.gracePeriod = gracePeriod
+	return c
+}
+
+// Grace period of the command, defaults to GracePeriod of the runner
+func (c *Command) grace(r *Runner) time.Duration {
+	if c.gracePeriod > 0 {
+		return c.gracePeriod
+	}
+	return r.GracePeriod
+}
+
+// Call fn for every line written to stdout while the command is running
+func (c *Command) OnStdoutLine(fn func(line string)) *Command {
+	c.stdoutLine = fn
+	return c
+}
+
+// Call fn for every line written to stderr while the command is running
+func (c *Command) OnStderrLine(fn func(line string)) *Command {
+	c.stderrLine = fn
+	return c
+}
+
+// Runner of the command, defaults to the package configuration
+func (c *Command) getRunner() *Runner {
+	if c.runner != nil {
+		return c.runner
+	}
+	return defaultRunner()
+}
+
 // Run command
 func (c *Command) Run() *Process {
-	VerboseFunc(c)
-	return c.execute(false)
+	return c.RunContext(c.context())
+}
+
+// Run command bound to ctx, the process group of the command
+// (including all piped commands) is stopped if ctx is done before
+// the command finishes
+func (c *Command) RunContext(ctx context.Context) *Process {
+	r := c.getRunner()
+	r.verbose(c)
+	p, err := c.execute(ctx, r, false)
+	return r.check(c, p, err)
+}
+
+// Run command and return failures as error instead of panicking,
+// failed commands return an *ExitError
+func (c *Command) RunE() (*Process, error) {
+	r := c.getRunner()
+	r.verbose(c)
+	return c.execute(c.context(), r, false)
 }
 
 // Run command in interactive mode
 func (c *Command) RunInteractive() *Process {
-	VerboseFunc(c)
-	return c.execute(true)
+	r := c.getRunner()
+	r.verbose(c)
+	p, err := c.execute(c.context(), r, true)
+	return r.check(c, p, err)
 }
 
-// Execute command
-func (c *Command) execute(interactive bool) *Process {
-	if Trace {
-		fmt.Fprintln(os.Stderr, TracePrefix, c.shellCmd(false))
-	}
-	cmd := exec.Command(Shell[0], append(Shell[1:], c.shellCmd(false))...)
-	p := new(Process)
-	p.Command = c
-	if c.in != nil {
-		cmd.Stdin = c.in.execute(false)
-	} else {
-		stdin, err := cmd.StdinPipe()
-		assert(err)
-		p.Stdin = stdin
-	}
+// Execute command through the middleware chain of the runner (retried
+// according to its policy), failures are reported to the runner hooks
+// and returned as error
+func (c *Command) execute(ctx context.Context, r *Runner, interactive bool) (*Process, error) {
+	handler := r.handler(func(ctx context.Context, c *Command) (*Process, error) {
+		pl, err := c.start(ctx, r, interactive)
+		if err != nil {
+			return pl.process, err
+		}
+		return pl.wait()
+	})
 
-	if interactive {
-		cmd.Stdout = os.Stdout
-		cmd.Stdin = os.Stdin
-		cmd.Stderr = os.Stderr
+	var p *Process
+	var err error
+	if c.retry != nil {
+		p, err = c.retry.run(ctx, c, handler)
 	} else {
-		var stdout bytes.Buffer
-		if Tee != nil {
-			cmd.Stdout = io.MultiWriter(&stdout, Tee)
-		} else {
-			cmd.Stdout = &stdout
-		}
-		p.Stdout = &stdout
-		var stderr bytes.Buffer
-		if Tee != nil {
-			cmd.Stderr = io.MultiWriter(&stderr, Tee)
-		} else {
-			cmd.Stderr = &stderr
-		}
-		p.Stderr = &stderr
+		p, err = handler(ctx, c)
 	}
-	err := cmd.Run()
-	if err != nil {
-		if exiterr, ok := err.(*exec.ExitError); ok {
-			if stat, ok := exiterr.Sys().(syscall.WaitStatus); ok {
-				p.ExitStatus = int(stat.ExitStatus())
-				ErrorFunc(c, p)
-				if Panic {
-					panic(p)
-				}
-			}
-		} else {
-			assert(err)
-		}
+	if p != nil || err == nil {
+		p = completeProcess(c, p)
 	}
-	return p
+	r.fail(c, p, err)
+	return p, err
 }
 
 // Create new Command instance
@@ -280,21 +378,83 @@ func Cmd(cmd ...interface{}) *Command {
 	return c
 }
 
+// Create new Command instance bound to ctx
+func CmdContext(ctx context.Context, cmd ...interface{}) *Command {
+	c := Cmd(cmd...)
+	c.ctx = ctx
+	return c
+}
+
 type Process struct {
 	Stdout     *bytes.Buffer
 	Stderr     *bytes.Buffer
 	Stdin      io.WriteCloser
 	ExitStatus int
 	Command    *Command
+
+	// Command was stopped because its context was done
+	Canceled bool
+
+	// Command was stopped because its timeout was reached
+	TimedOut bool
+
+	// Signal which terminated the command
+	Signal syscall.Signal
+
+	// Command dumped core when it was terminated by the signal
+	CoreDump bool
+
+	// Exit status of each piped command (like PIPESTATUS)
+	PipeStatus []int
+
+	// Exit state and resource usage of each piped command
+	Stages []ExitState
+
+	// Resource usage of all piped commands, CPU times and memory are
+	// summed up, duration is the wall-clock duration of the pipe
+	Usage Usage
+
+	// Output was truncated because of the capture limit of the runner,
+	// Stdout and Stderr contain the retained head and tail only
+	Truncated bool
+
+	// Temp files with the complete stdout and stderr if output was
+	// truncated and spilling is enabled, the files must be removed
+	// by the caller
+	StdoutFile string
+	StderrFile string
+
+	// Error which prevented the command from running (eg. ErrShellSyntax),
+	// only set by Run, RunContext, RunInteractive and Wait
+	Err error
+
+	// Failed attempts before this one if the command was retried,
+	// oldest first (see Command.Retry)
+	Attempts []*Process
+
+	combined []Chunk
+}
+
+// Mark process as timed out or canceled, depending on why ctx is done
+func (p *Process) interrupted(ctx context.Context) {
+	if context.Cause(ctx) == ErrTimeout {
+		p.TimedOut = true
+	} else {
+		p.Canceled = true
+	}
 }
 
 // Create human readable representation of process status
 func (p *Process) Debug() string {
 	msg := ""
 
-	stderr := strings.Replace(p.Stderr.String(), "\n", "\n           ", -1)
+	stderr := strings.Replace(p.Command.redact(p.Stderr.String()), "\n", "\n           ", -1)
 
-	if p.ExitStatus == 0 {
+	if p.TimedOut {
+		msg += "go-shell command timed out\n"
+	} else if p.Canceled {
+		msg += "go-shell command canceled\n"
+	} else if p.ExitStatus == 0 {
 		msg += "go-shell command executed successfully\n"
 	} else {
 		msg += "go-shell command failed\n"
@@ -302,7 +462,23 @@ func (p *Process) Debug() string {
 
 	msg += fmt.Sprintf("COMMAND:   %v\n", p.Command.ToString())
 	msg += fmt.Sprintf("EXIT CODE: %v\n", p.ExitStatus)
-	msg += fmt.Sprintf("STDERR:    %v\n", stderr)
+	if p.CoreDump {
+		msg += fmt.Sprintf("SIGNAL:    %v (core dumped)\n", p.Signal)
+	} else if p.Signal != 0 {
+		msg += fmt.Sprintf("SIGNAL:    %v\n", p.Signal)
+	}
+	msg += fmt.Sprintf("USAGE:     %v\n", p.Usage)
+	if len(p.Stages) > 1 {
+		for i, stage := range p.Stages {
+			msg += fmt.Sprintf("  STAGE %v:  exit code %v, %v\n", i+1, stage.ExitStatus, stage.Usage)
+		}
+	}
+	if p.combined != nil {
+		output := strings.Replace(p.Command.redact(p.transcript()), "\n", "\n           ", -1)
+		msg += fmt.Sprintf("OUTPUT:    %v\n", output)
+	} else {
+		msg += fmt.Sprintf("STDERR:    %v\n", stderr)
+	}
 	msg += "\n"
 
 	return msg
@@ -316,9 +492,24 @@ func (p *Process) Bytes() []byte {
 	return p.Stdout.Bytes()
 }
 
+// Create error of process status, returns an *ExitError
 func (p *Process) Error() error {
-	errlines := strings.Split(p.Stderr.String(), "\n")
-	return fmt.Errorf("[%v] %s\n", p.ExitStatus, errlines[len(errlines)-2])
+	e := &ExitError{
+		ExitStatus: p.ExitStatus,
+		Signal:     p.Signal,
+		TimedOut:   p.TimedOut,
+		Canceled:   p.Canceled,
+		Err:        p.Err,
+	}
+	if p.Stderr != nil && p.Truncated {
+		e.Stderr = p.Command.redact(truncatedTail(p.Stderr.String(), ExitErrorStderrLines))
+	} else if p.Stderr != nil {
+		e.Stderr = p.Command.redact(stderrTail(p.Stderr.String(), ExitErrorStderrLines))
+	}
+	if p.Command != nil {
+		e.Command = p.Command.ToString()
+	}
+	return e
 }
 
 func (p *Process) Read(b []byte) (int, error) {
@@ -137,3 +137,25 @@ func TestToString(t *testing.T) {
 		t.Fatal("output not expected:", command)
 	}
 }
+
+func TestErrExit(t *testing.T) {
+	defer func(fn func(int)) { exit = fn }(exit)
+	status := 0
+	exit = func(code int) { status = code }
+
+	func() {
+		defer ErrExit()
+		Cmd("cat").StdinFile("/nonexistent").Run()
+	}()
+	if status != -1 {
+		t.Fatal("exit status not expected:", status)
+	}
+
+	func() {
+		defer ErrExit()
+		Run("exit 3")
+	}()
+	if status != 3 {
+		t.Fatal("exit status not expected:", status)
+	}
+}
@@ -0,0 +1,274 @@
+package shelltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/webdevops/go-shell"
+)
+
+// Environment variable enabling recording for CassetteExecutor
+const RecordEnv = "GO_SHELL_RECORD"
+
+// Recorded command executions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Single recorded command execution
+type Interaction struct {
+	Command    string        `json:"command"`
+	Stdout     string        `json:"stdout"`
+	Stderr     string        `json:"stderr"`
+	ExitStatus int           `json:"exit_status"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// Load cassette from JSON file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cassette := new(Cassette)
+	if err := json.Unmarshal(data, cassette); err != nil {
+		return nil, fmt.Errorf("shelltest: invalid cassette %s: %w", path, err)
+	}
+	return cassette, nil
+}
+
+// Save cassette as JSON file
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Executor recording every executed command of the wrapped executor.
+// Secrets in commands and output are masked using Redactor before
+// they are recorded, so cassettes can be committed.
+type Recorder struct {
+	// Redactor for recorded commands and output
+	// (defaults to shell.DefaultRedactor)
+	Redactor *shell.Redactor
+
+	executor shell.Executor
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// Create recorder wrapping executor (defaults to shell.DefaultExecutor)
+func NewRecorder(executor shell.Executor) *Recorder {
+	if executor == nil {
+		executor = shell.DefaultExecutor
+	}
+	return &Recorder{Redactor: shell.DefaultRedactor, executor: executor}
+}
+
+// Create runner using the recorder
+func (r *Recorder) Runner() *shell.Runner {
+	return newRunner(r)
+}
+
+// Recorded command executions
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Cassette{
+		Interactions: append([]Interaction(nil), r.cassette.Interactions...),
+	}
+}
+
+// Save recorded command executions as JSON file
+func (r *Recorder) Save(path string) error {
+	return r.Cassette().Save(path)
+}
+
+// Start command using the wrapped executor and record its output
+func (r *Recorder) Start(s *shell.Spawn) (shell.Handle, error) {
+	h := &recordedHandle{
+		recorder: r,
+		spawn:    s,
+		start:    time.Now(),
+	}
+
+	// capture output using own pipes, pipes of the spawn are
+	// closed after the output was copied
+	stdout, err := h.capture(s.Stdout, &h.stdout)
+	if err != nil {
+		s.ClosePipes()
+		return nil, err
+	}
+	stderr, err := h.capture(s.Stderr, &h.stderr)
+	if err != nil {
+		stdout.Close()
+		h.copying.Wait()
+		s.ClosePipes()
+		return nil, err
+	}
+
+	inner := &shell.Spawn{
+		Command: s.Command,
+		Args:    s.Args,
+		Env:     s.Env,
+		Dir:     s.Dir,
+		Stdin:   s.Stdin,
+		Stdout:  stdout,
+		Stderr:  stderr,
+	}
+	handle, err := r.executor.Start(inner)
+	stdout.Close()
+	stderr.Close()
+	if err != nil {
+		h.copying.Wait()
+		s.ClosePipes()
+		return nil, err
+	}
+	h.Handle = handle
+
+	go func() {
+		h.copying.Wait()
+		s.ClosePipes()
+	}()
+
+	return h, nil
+}
+
+// Handle of a recorded process
+type recordedHandle struct {
+	shell.Handle
+
+	recorder *Recorder
+	spawn    *shell.Spawn
+	start    time.Time
+	stdout   bytes.Buffer
+	stderr   bytes.Buffer
+	copying  sync.WaitGroup
+}
+
+// Create pipe copying its data to w and buf
+func (h *recordedHandle) capture(w io.Writer, buf *bytes.Buffer) (*os.File, error) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	h.copying.Add(1)
+	go func() {
+		defer h.copying.Done()
+		io.Copy(io.MultiWriter(w, buf), pr)
+		pr.Close()
+	}()
+	return pw, nil
+}
+
+func (h *recordedHandle) Wait() (shell.ExitState, error) {
+	state, err := h.Handle.Wait()
+	h.copying.Wait()
+	if err != nil {
+		return state, err
+	}
+
+	h.recorder.mu.Lock()
+	defer h.recorder.mu.Unlock()
+	h.recorder.cassette.Interactions = append(h.recorder.cassette.Interactions, Interaction{
+		Command:    h.recorder.Redactor.Redact(h.spawn.String()),
+		Stdout:     h.recorder.Redactor.Redact(h.stdout.String()),
+		Stderr:     h.recorder.Redactor.Redact(h.stderr.String()),
+		ExitStatus: state.ExitStatus,
+		Duration:   time.Since(h.start),
+	})
+	return state, nil
+}
+
+// Executor serving recorded command executions, interactions are
+// served in recorded order for the same command. Commands are matched
+// in their redacted form, secrets have to be registered at Redactor
+// like during recording. Redacted output is served as recorded.
+type Replayer struct {
+	// Redactor for matching commands with recorded (redacted) commands
+	// (defaults to shell.DefaultRedactor)
+	Redactor *shell.Redactor
+
+	t        testing.TB
+	cassette *Cassette
+
+	mu   sync.Mutex
+	used []bool
+	pid  int
+}
+
+// Create replayer for cassette, the test fails for commands
+// which are not recorded
+func NewReplayer(t testing.TB, cassette *Cassette) *Replayer {
+	return &Replayer{
+		Redactor: shell.DefaultRedactor,
+		t:        t,
+		cassette: cassette,
+		used:     make([]bool, len(cassette.Interactions)),
+		pid:      10000,
+	}
+}
+
+// Create runner using the replayer
+func (r *Replayer) Runner() *shell.Runner {
+	return newRunner(r)
+}
+
+// Serve recorded output for the command
+func (r *Replayer) Start(s *shell.Spawn) (shell.Handle, error) {
+	command := r.Redactor.Redact(s.String())
+
+	r.mu.Lock()
+	var match *Interaction
+	for i := range r.cassette.Interactions {
+		if !r.used[i] && r.cassette.Interactions[i].Command == command {
+			r.used[i] = true
+			match = &r.cassette.Interactions[i]
+			break
+		}
+	}
+	r.pid++
+	pid := r.pid
+	r.mu.Unlock()
+
+	if match == nil {
+		s.ClosePipes()
+		r.t.Errorf("shelltest: command %q not recorded", command)
+		return nil, fmt.Errorf("shelltest: command %q not recorded", command)
+	}
+
+	return startFake(s, pid, match.Stdout, match.Stderr, match.ExitStatus), nil
+}
+
+// Executor replaying the cassette at path, commands are executed and
+// recorded to the cassette instead if RecordEnv is set
+func CassetteExecutor(t testing.TB, path string) shell.Executor {
+	t.Helper()
+
+	if os.Getenv(RecordEnv) != "" {
+		recorder := NewRecorder(nil)
+		t.Cleanup(func() {
+			if err := recorder.Save(path); err != nil {
+				t.Errorf("shelltest: saving cassette failed: %v", err)
+			}
+		})
+		return recorder
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("shelltest: loading cassette failed: %v", err)
+	}
+	return NewReplayer(t, cassette)
+}
@@ -0,0 +1,68 @@
+package shelltest
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/webdevops/go-shell"
+)
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder := NewRecorder(nil)
+	r := recorder.Runner()
+	r.Panic = false
+	recorded := r.Cmd("echo foobar; echo error >&2; exit 3").Pipe("tr a-z A-Z").Run()
+	if recorded.String() != "FOOBAR" || recorded.ExitStatus != 3 {
+		t.Fatal("process not expected:", recorded.String(), recorded.ExitStatus)
+	}
+
+	interactions := recorder.Cassette().Interactions
+	if len(interactions) != 2 {
+		t.Fatal("interactions not expected:", interactions)
+	}
+	if interactions[0].Command != "echo foobar; echo error >&2; exit 3" || interactions[0].Stdout != "foobar\n" || interactions[0].ExitStatus != 3 {
+		t.Fatal("interaction not expected:", interactions[0])
+	}
+	if err := recorder.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = NewReplayer(t, cassette).Runner()
+	r.Panic = false
+	replayed := r.Cmd("echo foobar; echo error >&2; exit 3").Pipe("tr a-z A-Z").Run()
+
+	if replayed.String() != recorded.String() || replayed.Stderr.String() != "error\n" {
+		t.Fatal("output not expected:", replayed.String(), replayed.Stderr.String())
+	}
+	if !reflect.DeepEqual(replayed.PipeStatus, recorded.PipeStatus) {
+		t.Fatal("pipe status not expected:", replayed.PipeStatus)
+	}
+}
+
+func TestRecordReplayRedacted(t *testing.T) {
+	redactor := shell.NewRedactor()
+	redactor.AddSecret("s3cr3t")
+
+	recorder := NewRecorder(nil)
+	recorder.Redactor = redactor
+	recorder.Runner().Cmd("echo", "s3cr3t").Run()
+
+	interactions := recorder.Cassette().Interactions
+	if len(interactions) != 1 || interactions[0].Command != "echo ***" || interactions[0].Stdout != "***\n" {
+		t.Fatal("interactions not expected:", interactions)
+	}
+
+	replayer := NewReplayer(t, recorder.Cassette())
+	replayer.Redactor = redactor
+	p := replayer.Runner().Cmd("echo", "s3cr3t").Run()
+	if p.String() != "***" {
+		t.Fatal("output not expected:", p.String())
+	}
+}
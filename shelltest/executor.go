@@ -0,0 +1,190 @@
+// Package shelltest provides a fake executor for testing code
+// using go-shell without running real processes.
+package shelltest
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/webdevops/go-shell"
+)
+
+// Fake executor serving canned results for expected commands, the test
+// fails for unexpected commands and expectations which were not met
+type Executor struct {
+	t testing.TB
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	pid          int
+}
+
+// Expected command with its canned result
+type Expectation struct {
+	command string
+	pattern *regexp.Regexp
+
+	stdout     string
+	stderr     string
+	exitStatus int
+	times      int
+	calls      int
+}
+
+// Create fake executor, expectations are checked when the test finishes
+func NewExecutor(t testing.TB) *Executor {
+	e := &Executor{t: t, pid: 10000}
+	t.Cleanup(e.AssertExpectations)
+	return e
+}
+
+// Create runner using the fake executor
+func (e *Executor) Runner() *shell.Runner {
+	return newRunner(e)
+}
+
+// Create runner using executor
+func newRunner(executor shell.Executor) *shell.Runner {
+	r := shell.NewRunner()
+	r.Executor = executor
+	return r
+}
+
+// Expect command matching exactly (eg. "docker-compose ps -q 'mysql'"),
+// piped commands are matched separately
+func (e *Executor) Expect(command string) *Expectation {
+	return e.add(&Expectation{command: command})
+}
+
+// Expect command matching the regular expression
+func (e *Executor) ExpectRegexp(pattern string) *Expectation {
+	return e.add(&Expectation{pattern: regexp.MustCompile(pattern)})
+}
+
+func (e *Executor) add(x *Expectation) *Expectation {
+	x.times = 1
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expectations = append(e.expectations, x)
+	return x
+}
+
+// Set stdout of the command
+func (x *Expectation) Stdout(stdout string) *Expectation {
+	x.stdout = stdout
+	return x
+}
+
+// Set stderr of the command
+func (x *Expectation) Stderr(stderr string) *Expectation {
+	x.stderr = stderr
+	return x
+}
+
+// Set exit status of the command
+func (x *Expectation) ExitStatus(status int) *Expectation {
+	x.exitStatus = status
+	return x
+}
+
+// Set how often the command is expected (defaults to 1)
+func (x *Expectation) Times(times int) *Expectation {
+	x.times = times
+	return x
+}
+
+func (x *Expectation) String() string {
+	if x.pattern != nil {
+		return fmt.Sprintf("/%s/", x.pattern)
+	}
+	return fmt.Sprintf("%q", x.command)
+}
+
+func (x *Expectation) match(command string) bool {
+	if x.pattern != nil {
+		return x.pattern.MatchString(command)
+	}
+	return x.command == command
+}
+
+// Fail the test for every expectation which was not met
+func (e *Executor) AssertExpectations() {
+	e.t.Helper()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, x := range e.expectations {
+		if x.calls != x.times {
+			e.t.Errorf("shelltest: command %s called %d times, expected %d times", x, x.calls, x.times)
+		}
+	}
+}
+
+// Start fake process for the first matching expectation
+func (e *Executor) Start(s *shell.Spawn) (shell.Handle, error) {
+	command := s.String()
+
+	e.mu.Lock()
+	var match *Expectation
+	for _, x := range e.expectations {
+		if x.calls < x.times && x.match(command) {
+			match = x
+			break
+		}
+	}
+	if match != nil {
+		match.calls++
+	}
+	e.pid++
+	pid := e.pid
+	e.mu.Unlock()
+
+	if match == nil {
+		s.ClosePipes()
+		e.t.Errorf("shelltest: unexpected command %q", command)
+		return nil, fmt.Errorf("shelltest: unexpected command %q", command)
+	}
+
+	return startFake(s, pid, match.stdout, match.stderr, match.exitStatus), nil
+}
+
+// Start fake process writing the canned output
+func startFake(s *shell.Spawn, pid int, stdout, stderr string, exitStatus int) shell.Handle {
+	h := &handle{
+		pid:   pid,
+		state: shell.ExitState{ExitStatus: exitStatus},
+		done:  make(chan struct{}),
+	}
+	go func() {
+		io.WriteString(s.Stdout, stdout)
+		io.WriteString(s.Stderr, stderr)
+		s.ClosePipes()
+		close(h.done)
+	}()
+	return h
+}
+
+// Handle of a fake process
+type handle struct {
+	pid   int
+	state shell.ExitState
+	done  chan struct{}
+}
+
+func (h *handle) Pid() int {
+	return h.pid
+}
+
+func (h *handle) Signal(sig syscall.Signal) error {
+	return nil
+}
+
+func (h *handle) Wait() (shell.ExitState, error) {
+	<-h.done
+	return h.state, nil
+}
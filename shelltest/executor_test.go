@@ -0,0 +1,68 @@
+package shelltest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/webdevops/go-shell"
+)
+
+func TestExecutor(t *testing.T) {
+	e := NewExecutor(t)
+	e.Expect("docker-compose ps -q 'mysql'").Stdout("32ceb49d2958\n")
+	e.ExpectRegexp(`^docker inspect `).Stdout("FOO=bar\n").Times(2)
+
+	r := e.Runner()
+	if out := r.Cmd("docker-compose", "ps", "-q", shell.Quote("mysql")).Run().String(); out != "32ceb49d2958" {
+		t.Fatal("output not expected:", out)
+	}
+	for i := 0; i < 2; i++ {
+		if out := r.Run("docker inspect 32ceb49d2958").String(); out != "FOO=bar" {
+			t.Fatal("output not expected:", out)
+		}
+	}
+}
+
+func TestExecutorPipe(t *testing.T) {
+	e := NewExecutor(t)
+	e.Expect("cat /etc/passwd").Stdout("root:x:0:0\n")
+	e.Expect("grep root").Stderr("failed\n").ExitStatus(2)
+
+	p, err := e.Runner().Cmd("cat /etc/passwd").Pipe("grep root").RunE()
+	if !reflect.DeepEqual(p.PipeStatus, []int{0, 2}) {
+		t.Fatal("pipe status not expected:", p.PipeStatus)
+	}
+
+	var exitErr *shell.ExitError
+	if !errors.As(err, &exitErr) || exitErr.Stderr != "failed\n" {
+		t.Fatal("error not expected:", err)
+	}
+}
+
+func TestExecutorUnexpected(t *testing.T) {
+	tb := &recorder{TB: t}
+	e := &Executor{t: tb}
+	e.Expect("echo foobar")
+
+	if _, err := e.Runner().Cmd("echo barfoo").RunE(); err == nil {
+		t.Fatal("error expected")
+	}
+	e.AssertExpectations()
+
+	if len(tb.errors) != 2 {
+		t.Fatal("test errors not expected:", tb.errors)
+	}
+}
+
+// Records test failures instead of failing the test
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, format)
+}
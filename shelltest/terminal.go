@@ -0,0 +1,120 @@
+package shelltest
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Fake terminal for commands running with a pseudo-terminal
+// (see shell.Command.Pty)
+type Terminal struct {
+	in io.Reader
+
+	mu       sync.Mutex
+	out      bytes.Buffer
+	rows     int
+	cols     int
+	raw      bool
+	restored bool
+	resize   []chan<- struct{}
+}
+
+// Create fake terminal with 24 rows and 80 columns reading the user
+// input from in (no input if nil)
+func NewTerminal(in io.Reader) *Terminal {
+	if in == nil {
+		in = bytes.NewReader(nil)
+	}
+	return &Terminal{in: in, rows: 24, cols: 80}
+}
+
+func (t *Terminal) Read(b []byte) (int, error) {
+	return t.in.Read(b)
+}
+
+// Interrupt pending reads if the input supports deadlines (eg. os.Pipe)
+func (t *Terminal) SetReadDeadline(deadline time.Time) error {
+	if in, ok := t.in.(interface{ SetReadDeadline(time.Time) error }); ok {
+		return in.SetReadDeadline(deadline)
+	}
+	return os.ErrNoDeadline
+}
+
+func (t *Terminal) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.out.Write(b)
+}
+
+// Output written to the terminal
+func (t *Terminal) Output() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.out.String()
+}
+
+func (t *Terminal) MakeRaw() (func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.raw = true
+	t.restored = false
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.raw = false
+		t.restored = true
+	}, nil
+}
+
+// Terminal is in raw mode
+func (t *Terminal) Raw() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.raw
+}
+
+// Terminal was restored after raw mode
+func (t *Terminal) Restored() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.restored
+}
+
+func (t *Terminal) Size() (int, int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rows, t.cols, nil
+}
+
+// Change size of the terminal and notify running commands
+func (t *Terminal) Resize(rows, cols int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rows = rows
+	t.cols = cols
+	for _, ch := range t.resize {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (t *Terminal) NotifyResize(ch chan<- struct{}) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.resize = append(t.resize, ch)
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		for i, c := range t.resize {
+			if c == ch {
+				t.resize = append(t.resize[:i], t.resize[i+1:]...)
+				break
+			}
+		}
+	}
+}
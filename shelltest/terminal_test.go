@@ -0,0 +1,80 @@
+//go:build linux
+
+package shelltest
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webdevops/go-shell"
+)
+
+func TestTerminal(t *testing.T) {
+	term := NewTerminal(strings.NewReader("foobar\n"))
+	r := shell.NewRunner()
+
+	p := r.Cmd("tty; stty size; read x; echo got $x").Pty(term).Run()
+	output := term.Output()
+	if !strings.Contains(output, "/dev/pts/") || !strings.Contains(output, "24 80") || !strings.Contains(output, "got foobar") {
+		t.Fatal("output not expected:", output)
+	}
+	if p.Stdout.String() != output {
+		t.Fatal("captured output not expected:", p.String())
+	}
+	if term.Raw() || !term.Restored() {
+		t.Fatal("terminal not restored")
+	}
+}
+
+func TestTerminalResize(t *testing.T) {
+	term := NewTerminal(nil)
+	r := shell.NewRunner()
+
+	cmd := r.Cmd(`while [ "$(stty size)" != "40 120" ]; do sleep 0.05; done; echo resized`).
+		Pty(term).
+		WithTimeout(10 * time.Second)
+	rp, err := cmd.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !term.Raw() {
+		t.Fatal("terminal not in raw mode")
+	}
+	term.Resize(40, 120)
+
+	p, err := rp.WaitE()
+	if err != nil || !strings.Contains(term.Output(), "resized") {
+		t.Fatal("terminal not resized:", err, p.Debug())
+	}
+}
+
+func TestTerminalPipe(t *testing.T) {
+	r := shell.NewRunner()
+	_, err := r.Cmd("echo foobar").Pipe("cat").Pty(NewTerminal(nil)).RunE()
+	if err == nil || !strings.Contains(err.Error(), "piped commands") {
+		t.Fatal("error not expected:", err)
+	}
+}
+
+func TestTerminalInputStopped(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	defer pw.Close()
+
+	r := shell.NewRunner()
+	r.Cmd("true").Pty(NewTerminal(pr)).Run()
+
+	// input after the command is finished must not be consumed
+	io.WriteString(pw, "foobar")
+	pr.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b := make([]byte, 6)
+	if n, err := pr.Read(b); err != nil || string(b[:n]) != "foobar" {
+		t.Fatal("input not expected:", string(b[:n]), err)
+	}
+}
@@ -0,0 +1,46 @@
+package shell
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+)
+
+// Set stdin of the command, for piped commands the input is passed to
+// the first command. The reader is consumed by the first execution.
+func (c *Command) WithStdin(r io.Reader) *Command {
+	c.stdin = func() (io.Reader, io.Closer, error) {
+		return r, nil, nil
+	}
+	return c
+}
+
+// Set stdin of the command to a string
+func (c *Command) StdinString(s string) *Command {
+	c.stdin = func() (io.Reader, io.Closer, error) {
+		return strings.NewReader(s), nil, nil
+	}
+	return c
+}
+
+// Set stdin of the command to a byte slice
+func (c *Command) StdinBytes(b []byte) *Command {
+	c.stdin = func() (io.Reader, io.Closer, error) {
+		return bytes.NewReader(b), nil, nil
+	}
+	return c
+}
+
+// Set stdin of the command to the content of a file, the file
+// is opened for each execution and closed afterwards
+func (c *Command) StdinFile(path string) *Command {
+	c.stdin = func() (io.Reader, io.Closer, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return f, f, nil
+	}
+	return c
+}
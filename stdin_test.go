@@ -0,0 +1,69 @@
+package shell
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStdinString(t *testing.T) {
+	cmd := Cmd("cat").StdinString("foobar")
+	for i := 0; i < 2; i++ {
+		if output := cmd.Run().String(); output != "foobar" {
+			t.Fatal("output not expected:", output)
+		}
+	}
+}
+
+func TestStdinPipe(t *testing.T) {
+	p := Cmd("tr a-z A-Z").Pipe("cat").WithStdin(strings.NewReader("foobar")).Run()
+	if p.String() != "FOOBAR" {
+		t.Fatal("output not expected:", p.String())
+	}
+
+	p = Cmd("wc -c").Pipe("tr -d ' '").StdinBytes([]byte("foobar")).Run()
+	if p.String() != "6" {
+		t.Fatal("output not expected:", p.String())
+	}
+}
+
+func TestStdinFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(path, []byte("foobar\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if output := Cmd("cat").StdinFile(path).Run().String(); output != "foobar" {
+		t.Fatal("output not expected:", output)
+	}
+
+	_, err := Cmd("cat").StdinFile(path + ".missing").RunE()
+	if !os.IsNotExist(err) {
+		t.Fatal("error not expected:", err)
+	}
+}
+
+func TestStdinFileMissing(t *testing.T) {
+	r := NewRunner()
+	r.Panic = false
+
+	p := r.Cmd("cat").StdinFile("/nonexistent").Run()
+	if p.ExitStatus != -1 || !errors.Is(p.Err, os.ErrNotExist) || !strings.Contains(p.Stderr.String(), "/nonexistent") {
+		t.Fatal("process not expected:", p.ExitStatus, p.Err)
+	}
+
+	r.Panic = true
+	out, err := r.Cmd("cat").StdinFile("/nonexistent").OutputFn()()
+	if out != "" || !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("error not expected:", out, err)
+	}
+
+	defer func() {
+		if p, ok := recover().(*Process); !ok || !errors.Is(p.Error(), os.ErrNotExist) {
+			t.Fatal("panic not expected:", p)
+		}
+	}()
+	r.Cmd("cat").StdinFile("/nonexistent").Run()
+}
@@ -0,0 +1,216 @@
+package shell
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// Writer calling a func for every complete line written to it
+type lineWriter struct {
+	fn  func(string)
+	buf []byte
+}
+
+// Create line writer for fn, returns nil if fn is nil
+func newLineWriter(fn func(string)) *lineWriter {
+	if fn == nil {
+		return nil
+	}
+	return &lineWriter{fn: fn}
+}
+
+func (w *lineWriter) Write(b []byte) (int, error) {
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i == -1 {
+			break
+		}
+		w.fn(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// Pass remaining output without trailing newline to the func
+func (w *lineWriter) Flush() {
+	if w == nil || len(w.buf) == 0 {
+		return
+	}
+	w.fn(string(w.buf))
+	w.buf = nil
+}
+
+// Combine capture buffer with optional tee and line writer
+func outputWriter(buf io.Writer, tee io.Writer, lines *lineWriter) io.Writer {
+	writers := []io.Writer{buf}
+	if tee != nil {
+		writers = append(writers, tee)
+	}
+	if lines != nil {
+		writers = append(writers, lines)
+	}
+	if len(writers) == 1 {
+		return buf
+	}
+	return io.MultiWriter(writers...)
+}
+
+// Writer serializing writes of concurrent commands
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(b)
+}
+
+// Output buffer of a running command which can be read while
+// the command is still writing to it. With a capture limit only
+// the head is kept in buf while the command is running, the tail
+// is appended when the buffer is closed.
+type captureBuffer struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	buf     *bytes.Buffer
+	closed  bool
+	limit   CaptureLimit
+	written int64
+	tail    *ringBuffer
+	spill   *os.File
+}
+
+func newCaptureBuffer(limit CaptureLimit) (*captureBuffer, error) {
+	b := &captureBuffer{buf: new(bytes.Buffer), limit: limit}
+	b.cond = sync.NewCond(&b.mu)
+	if limit.Tail > 0 {
+		b.tail = newRingBuffer(limit.Tail)
+	}
+	if limit.enabled() && limit.Spill {
+		spill, err := createSpillFile()
+		if err != nil {
+			return b, err
+		}
+		b.spill = spill
+	}
+	return b, nil
+}
+
+func (b *captureBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	defer b.cond.Broadcast()
+
+	b.written += int64(len(p))
+	if b.spill != nil {
+		if _, err := b.spill.Write(p); err != nil {
+			b.removeSpill()
+		}
+	}
+	if !b.limit.enabled() {
+		return b.buf.Write(p)
+	}
+
+	head := min(max(b.limit.Head-b.buf.Len(), 0), len(p))
+	b.buf.Write(p[:head])
+	if b.tail != nil {
+		b.tail.Write(p[head:])
+	}
+	return len(p), nil
+}
+
+// Number of bytes written to the buffer
+func (b *captureBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return int(b.written)
+}
+
+// Number of bytes which were not retained
+func (b *captureBuffer) truncated() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.limit.enabled() {
+		return 0
+	}
+	return max(b.written-int64(b.limit.Head)-int64(b.limit.Tail), 0)
+}
+
+// Path of the temp file with the complete output, empty if
+// the output was not spilled
+func (b *captureBuffer) spillFile() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.spill == nil {
+		return ""
+	}
+	return b.spill.Name()
+}
+
+// Mark output as complete, readers receive io.EOF afterwards.
+// A marker is appended to the head if output was discarded, followed
+// by the retained tail.
+func (b *captureBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+
+	if truncated := b.written - int64(b.limit.Head) - int64(b.limit.Tail); b.limit.enabled() && truncated > 0 {
+		if b.buf.Len() > 0 && !bytes.HasSuffix(b.buf.Bytes(), []byte("\n")) {
+			b.buf.WriteString("\n")
+		}
+		b.buf.WriteString(truncatedMarker(truncated))
+	}
+	if b.tail != nil {
+		b.buf.Write(b.tail.Bytes())
+	}
+	if b.spill != nil {
+		b.spill.Close()
+		if b.written <= int64(b.limit.Head)+int64(b.limit.Tail) {
+			b.removeSpill()
+		}
+	}
+
+	b.closed = true
+	b.cond.Broadcast()
+	return nil
+}
+
+// Discard the spill file
+func (b *captureBuffer) removeSpill() {
+	b.spill.Close()
+	os.Remove(b.spill.Name())
+	b.spill = nil
+}
+
+// Create reader returning the output from the beginning,
+// reads block until new output is written or the buffer is closed
+func (b *captureBuffer) NewReader() io.Reader {
+	return &captureReader{b: b}
+}
+
+type captureReader struct {
+	b   *captureBuffer
+	off int
+}
+
+func (r *captureReader) Read(p []byte) (int, error) {
+	r.b.mu.Lock()
+	defer r.b.mu.Unlock()
+	for r.off >= r.b.buf.Len() && !r.b.closed {
+		r.b.cond.Wait()
+	}
+	if r.off >= r.b.buf.Len() {
+		return 0, io.EOF
+	}
+	n := copy(p, r.b.buf.Bytes()[r.off:])
+	r.off += n
+	return n, nil
+}
@@ -0,0 +1,24 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOnLine(t *testing.T) {
+	var stdout, stderr []string
+	p := Cmd("echo foo; echo bar >&2; printf baz").
+		OnStdoutLine(func(line string) { stdout = append(stdout, line) }).
+		OnStderrLine(func(line string) { stderr = append(stderr, line) }).
+		Run()
+
+	if !reflect.DeepEqual(stdout, []string{"foo", "baz"}) {
+		t.Fatal("stdout lines not expected:", stdout)
+	}
+	if !reflect.DeepEqual(stderr, []string{"bar"}) {
+		t.Fatal("stderr lines not expected:", stderr)
+	}
+	if p.Stdout.String() != "foo\nbaz" || p.Stderr.String() != "bar\n" {
+		t.Fatal("output not expected:", p.Stdout.String(), p.Stderr.String())
+	}
+}
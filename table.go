@@ -0,0 +1,259 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Whitespace aligned table output (eg. docker ps, df or ps aux)
+type Table struct {
+	// Column names from the header line
+	Columns []string
+
+	// Rows with values by column name
+	Rows []map[string]string
+
+	command string
+}
+
+// Column of a table (or word of a line), position in characters
+type tableColumn struct {
+	name  string
+	start int
+	end   int
+}
+
+// Parse whitespace aligned table output, columns are anchored at the
+// words of the header line. Values are assigned to the column whose
+// header they overlap (or the nearest one), so right-aligned values
+// wider than their header work as well. Values and column names may
+// contain single spaces.
+func (p *Process) Table() (*Table, error) {
+	var lines [][]rune
+	for line := range p.IterLines() {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, []rune(line))
+		}
+	}
+	if len(lines) == 0 {
+		return nil, p.decodeError("table", errors.New("header line not found"))
+	}
+
+	columns := tableColumns(lines)
+	t := &Table{}
+	if p.Command != nil {
+		t.command = p.Command.ToString()
+	}
+	for _, column := range columns {
+		t.Columns = append(t.Columns, column.name)
+	}
+	for _, line := range lines[1:] {
+		t.Rows = append(t.Rows, tableRow(line, columns))
+	}
+	return t, nil
+}
+
+// Infer columns from the words of the header line, words separated by
+// a single space are one column name (eg. "CONTAINER ID") unless values
+// end below the first word or start below the second word
+func tableColumns(lines [][]rune) []tableColumn {
+	var columns []tableColumn
+	for _, word := range tableWords(lines[0]) {
+		if n := len(columns); n > 0 && word.start-columns[n-1].end == 1 && !tableBoundary(lines[1:], columns[n-1].end, word.start) {
+			columns[n-1].end = word.end
+			continue
+		}
+		columns = append(columns, word)
+	}
+	for i := range columns {
+		columns[i].name = string(lines[0][columns[i].start:columns[i].end])
+	}
+	return columns
+}
+
+// Words of a line separated by whitespace
+func tableWords(line []rune) []tableColumn {
+	var words []tableColumn
+	for pos, r := range line {
+		if r == ' ' || r == '\t' {
+			continue
+		}
+		if len(words) == 0 || words[len(words)-1].end < pos {
+			words = append(words, tableColumn{start: pos})
+		}
+		words[len(words)-1].end = pos + 1
+	}
+	return words
+}
+
+// Check if a word of any line ends at end or starts at start
+func tableBoundary(lines [][]rune, end, start int) bool {
+	for _, line := range lines {
+		for _, word := range tableWords(line) {
+			if word.end == end || word.start == start {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Assign the words of line to columns, words keep the order of
+// the columns and words separated by a single space outside of any
+// header belong to the same value
+func tableRow(line []rune, columns []tableColumn) map[string]string {
+	values := make([]tableColumn, len(columns))
+	for i := range values {
+		values[i].start = -1
+	}
+
+	current := 0
+	var prev *tableColumn
+	for _, word := range tableWords(line) {
+		i := tableOverlap(word, columns, current)
+		if i == -1 && prev != nil && word.start-prev.end == 1 {
+			i = current
+		} else if i == -1 {
+			i = max(tableNearest(word, columns), current)
+		}
+
+		if values[i].start == -1 {
+			values[i].start = word.start
+		}
+		values[i].end = word.end
+		current = i
+		prev = &word
+	}
+
+	row := map[string]string{}
+	for i, column := range columns {
+		row[column.name] = ""
+		if values[i].start != -1 {
+			row[column.name] = string(line[values[i].start:values[i].end])
+		}
+	}
+	return row
+}
+
+// Column from index from which overlaps most with word, -1 if none
+func tableOverlap(word tableColumn, columns []tableColumn, from int) int {
+	best, overlap := -1, 0
+	for i := from; i < len(columns); i++ {
+		if n := min(word.end, columns[i].end) - max(word.start, columns[i].start); n > overlap {
+			best, overlap = i, n
+		}
+	}
+	return best
+}
+
+// Column with the header nearest to word (which overlaps no header)
+func tableNearest(word tableColumn, columns []tableColumn) int {
+	for i, column := range columns {
+		if column.start < word.end {
+			continue
+		}
+		if i == 0 || column.start-word.end < word.start-columns[i-1].end {
+			return i
+		}
+		return i - 1
+	}
+	return len(columns) - 1
+}
+
+// Scan rows into v, which must be a pointer to a slice of structs.
+// Struct fields are mapped to columns with the tag `table:"COLUMN"`,
+// fields without tag are matched case-insensitively by name.
+func (t *Table) Scan(v interface{}) error {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Pointer || ptr.Elem().Kind() != reflect.Slice || ptr.Elem().Type().Elem().Kind() != reflect.Struct {
+		return t.decodeError(fmt.Errorf("cannot scan into %T, pointer to slice of structs expected", v))
+	}
+	slice := ptr.Elem()
+	elemType := slice.Type().Elem()
+
+	for i, row := range t.Rows {
+		elem := reflect.New(elemType).Elem()
+		for f := 0; f < elemType.NumField(); f++ {
+			field := elemType.Field(f)
+			if !field.IsExported() {
+				continue
+			}
+			value, ok := t.lookup(row, field)
+			if !ok {
+				continue
+			}
+			if err := setTableField(elem.Field(f), value); err != nil {
+				return t.decodeError(fmt.Errorf("row %v, field %s: %w", i+1, field.Name, err))
+			}
+		}
+		slice.Set(reflect.Append(slice, elem))
+	}
+	return nil
+}
+
+// Value of the column for struct field
+func (t *Table) lookup(row map[string]string, field reflect.StructField) (string, bool) {
+	if name, ok := field.Tag.Lookup("table"); ok {
+		if name == "-" {
+			return "", false
+		}
+		value, ok := row[name]
+		return value, ok
+	}
+	for _, column := range t.Columns {
+		if strings.EqualFold(column, field.Name) {
+			return row[column], true
+		}
+	}
+	return "", false
+}
+
+// Set struct field from table value
+func setTableField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+		return nil
+	}
+
+	if value == "" {
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(v)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(v)
+	default:
+		return fmt.Errorf("unsupported type %v", field.Type())
+	}
+	return nil
+}
+
+// Create decode error for table of command
+func (t *Table) decodeError(err error) error {
+	return &DecodeError{Command: t.command, Format: "table", Err: err}
+}
@@ -0,0 +1,116 @@
+package shell
+
+import (
+	"errors"
+	"testing"
+)
+
+func tableProcess(output string) *Process {
+	return Cmd("printf", "%s", Quote(output)).Run()
+}
+
+func TestTable(t *testing.T) {
+	p := tableProcess(`CONTAINER ID   IMAGE          COMMAND                  STATUS          PORTS      NAMES
+32ceb49d2958   mysql:5.7      "docker-entrypoint.s…"   Up 2 hours                 project_mysql_1
+8f7b0e1c2d3a   nginx:latest   "nginx -g 'daemon of…"   Up 45 minutes   80/tcp     project_web_1
+`)
+
+	table, err := p.Table()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Columns) != 6 || table.Columns[0] != "CONTAINER ID" || table.Columns[4] != "PORTS" {
+		t.Fatal("columns not expected:", table.Columns)
+	}
+	if len(table.Rows) != 2 {
+		t.Fatal("rows not expected:", table.Rows)
+	}
+	row := table.Rows[1]
+	if row["CONTAINER ID"] != "8f7b0e1c2d3a" || row["COMMAND"] != `"nginx -g 'daemon of…"` || row["STATUS"] != "Up 45 minutes" || row["PORTS"] != "80/tcp" {
+		t.Fatal("row not expected:", row)
+	}
+	if table.Rows[0]["PORTS"] != "" || table.Rows[0]["NAMES"] != "project_mysql_1" {
+		t.Fatal("row not expected:", table.Rows[0])
+	}
+}
+
+func TestTableRightAligned(t *testing.T) {
+	p := tableProcess(`Filesystem      Size  Used Avail Use% Mounted on
+/dev/sda1        20G  5.0G   14G  27% /
+tmpfs           1.9G     0  1.9G   0% /
+`)
+
+	table, err := p.Table()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Columns) != 6 || table.Columns[5] != "Mounted on" {
+		t.Fatal("columns not expected:", table.Columns)
+	}
+	row := table.Rows[1]
+	if row["Filesystem"] != "tmpfs" || row["Size"] != "1.9G" || row["Used"] != "0" || row["Use%"] != "0%" || row["Mounted on"] != "/" {
+		t.Fatal("row not expected:", row)
+	}
+}
+
+func TestTablePsAux(t *testing.T) {
+	p := tableProcess(`USER       PID %CPU %MEM    VSZ   RSS TTY      STAT START   TIME COMMAND
+root         1  0.2  0.1  24648 10072 ?        SLl  Oct16   0:09 /sbin/init --log-level info
+root         2  0.0  0.0      0     0 ?        S    Oct16   0:00 [kthreadd]
+mysql    14496  1.9  4.8 5703196 300264 ?      Sl   00:52   0:05 /usr/sbin/mysqld --user mysql
+www-data 20001  0.0  0.1 215432  9876 pts/0    S+   10:01   0:00 nginx: worker process
+`)
+
+	table, err := p.Table()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Columns) != 11 || table.Columns[4] != "VSZ" || table.Columns[5] != "RSS" || table.Columns[6] != "TTY" {
+		t.Fatal("columns not expected:", table.Columns)
+	}
+	for i, expected := range []map[string]string{
+		{"PID": "1", "VSZ": "24648", "RSS": "10072", "TTY": "?", "STAT": "SLl", "COMMAND": "/sbin/init --log-level info"},
+		{"PID": "2", "VSZ": "0", "RSS": "0", "TTY": "?", "STAT": "S", "COMMAND": "[kthreadd]"},
+		{"PID": "14496", "VSZ": "5703196", "RSS": "300264", "TTY": "?", "STAT": "Sl", "TIME": "0:05", "COMMAND": "/usr/sbin/mysqld --user mysql"},
+		{"USER": "www-data", "VSZ": "215432", "RSS": "9876", "TTY": "pts/0", "START": "10:01", "COMMAND": "nginx: worker process"},
+	} {
+		for column, value := range expected {
+			if table.Rows[i][column] != value {
+				t.Fatal("row not expected:", i, column, table.Rows[i])
+			}
+		}
+	}
+}
+
+func TestTableScan(t *testing.T) {
+	p := tableProcess(`NAME     PID  CPU    COMMAND
+mysqld   42   12.5   /usr/sbin/mysqld --user mysql
+nginx    7    0.1    nginx: master process
+`)
+
+	var rows []struct {
+		Name    string
+		Pid     int
+		Cpu     float64 `table:"CPU"`
+		Command string  `table:"COMMAND"`
+		Ignored string  `table:"-"`
+	}
+	table, err := p.Table()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Name != "mysqld" || rows[0].Pid != 42 || rows[0].Cpu != 12.5 || rows[1].Command != "nginx: master process" {
+		t.Fatal("rows not expected:", rows)
+	}
+
+	var invalid []struct {
+		Name int
+	}
+	var decodeErr *DecodeError
+	if err := table.Scan(&invalid); !errors.As(err, &decodeErr) || decodeErr.Format != "table" {
+		t.Fatal("error not expected:", err)
+	}
+}
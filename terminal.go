@@ -0,0 +1,188 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Local terminal connected to the pseudo-terminal of a command
+// (see Command.Pty), shelltest provides a fake terminal for tests.
+// Terminals implementing SetReadDeadline (like *os.File) stop being
+// read as soon as the command is finished, others are read until the
+// next input after the command is finished.
+type Terminal interface {
+	// Input typed by the user
+	io.Reader
+
+	// Output of the command
+	io.Writer
+
+	// Switch terminal to raw mode, restore is called when the
+	// command is finished
+	MakeRaw() (restore func(), err error)
+
+	// Current size of the terminal
+	Size() (rows, cols int, err error)
+
+	// Send to ch whenever the terminal is resized until stop is called
+	NotifyResize(ch chan<- struct{}) (stop func())
+}
+
+// Terminal of the current process using os.Stdin and os.Stdout
+func LocalTerminal() Terminal {
+	return &localTerminal{in: os.Stdin, out: os.Stdout}
+}
+
+type localTerminal struct {
+	in  *os.File
+	out *os.File
+}
+
+func (t *localTerminal) Read(b []byte) (int, error) {
+	return t.in.Read(b)
+}
+
+func (t *localTerminal) Write(b []byte) (int, error) {
+	return t.out.Write(b)
+}
+
+// Raw mode is skipped if stdin is not a terminal (eg. in CI)
+func (t *localTerminal) MakeRaw() (func(), error) {
+	return makeRaw(t.in)
+}
+
+func (t *localTerminal) Size() (int, int, error) {
+	return getWinsize(t.out)
+}
+
+func (t *localTerminal) NotifyResize(ch chan<- struct{}) func() {
+	return notifyResize(ch)
+}
+
+// Run command with a pseudo-terminal connected to term (LocalTerminal
+// if nil) instead of pipes, so programs like mysql or vim behave like
+// in an interactive shell. The terminal is put into raw mode while the
+// command is running and resizes are forwarded. Stdout and stderr are
+// both written to the terminal and captured as Stdout. Only supported
+// on linux and not for commands connected with Pipe (use inline pipes).
+func (c *Command) Pty(term Terminal) *Command {
+	if term == nil {
+		term = LocalTerminal()
+	}
+	c.terminal = term
+	return c
+}
+
+// Connect pseudo-terminal with stdio of the command and term,
+// output is additionally written to out
+func (pl *pipeline) attachTerminal(term Terminal, out io.Writer) error {
+	if len(pl.stages) > 1 {
+		return fmt.Errorf("%w: piped commands", ErrPtyNotSupported)
+	}
+	s := pl.stages[0]
+
+	master, slave, err := openPty()
+	if err != nil {
+		return err
+	}
+	s.spawn.Stdin = slave
+	s.spawn.Stdout = slave
+	s.spawn.Stderr = slave
+	s.spawn.Terminal = true
+	s.spawn.addPipe(slave)
+
+	if rows, cols, err := term.Size(); err == nil {
+		setWinsize(master, rows, cols)
+	}
+
+	restore, err := term.MakeRaw()
+	if err != nil {
+		master.Close()
+		return err
+	}
+
+	resized := make(chan struct{}, 1)
+	stopResize := term.NotifyResize(resized)
+	done := make(chan struct{})
+	resizeStopped := make(chan struct{})
+	go func() {
+		defer close(resizeStopped)
+		for {
+			select {
+			case <-done:
+				return
+			case <-resized:
+				if rows, cols, err := term.Size(); err == nil {
+					setWinsize(master, rows, cols)
+				}
+			}
+		}
+	}()
+
+	stopInput := forwardInput(term, master)
+
+	// output ends with an error as soon as the command is finished
+	s.copied = make(chan struct{})
+	go func() {
+		io.Copy(io.MultiWriter(term, out), master)
+		close(s.copied)
+	}()
+
+	s.closeAfterWait = append(s.closeAfterWait, closerFunc(func() error {
+		stopInput()
+		stopResize()
+		close(done)
+		<-resizeStopped
+		restore()
+		return master.Close()
+	}))
+	return nil
+}
+
+// Input which can be interrupted by a deadline
+type deadlineReader interface {
+	io.Reader
+	SetReadDeadline(t time.Time) error
+}
+
+// Copy input of term to the pseudo-terminal until stop is called.
+// Stdin of LocalTerminal is read using a non-blocking duplicate,
+// so pending reads can be interrupted without losing input.
+func forwardInput(term Terminal, master *os.File) (stop func()) {
+	var in io.Reader = term
+	cleanup := func() {}
+	if t, ok := term.(*localTerminal); ok {
+		if f, restore, err := pollableDup(t.in); err == nil {
+			in = f
+			cleanup = func() {
+				f.Close()
+				restore()
+			}
+		}
+	}
+
+	copied := make(chan struct{})
+	go func() {
+		// hide ReadFrom of master, copying has to use plain reads
+		io.Copy(struct{ io.Writer }{master}, in)
+		close(copied)
+	}()
+
+	return func() {
+		if d, ok := in.(deadlineReader); ok && d.SetReadDeadline(time.Now()) == nil {
+			master.SetWriteDeadline(time.Now())
+			<-copied
+			d.SetReadDeadline(time.Time{})
+		}
+		cleanup()
+	}
+}
+
+// Adapter for using a func as io.Closer
+type closerFunc func() error
+
+func (fn closerFunc) Close() error {
+	return fn()
+}